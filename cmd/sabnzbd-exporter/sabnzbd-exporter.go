@@ -12,10 +12,12 @@ import (
 
 	"prometheus-sabnzbd-exporter/internal/config"
 	"prometheus-sabnzbd-exporter/internal/exporter"
+	"prometheus-sabnzbd-exporter/internal/probe"
+	"prometheus-sabnzbd-exporter/internal/redact"
+	"prometheus-sabnzbd-exporter/pkg/client"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -56,6 +58,8 @@ func main() {
 		log.Fatal().Err(err).Msg("Invalid config")
 	}
 
+	cfg.RegisterSecrets()
+
 	logLevel, err := zerolog.ParseLevel(cfg.LogLevel)
 	if err != nil {
 		// Sanity Check, should be unreachable due to validation.
@@ -66,6 +70,9 @@ func main() {
 
 	var srv http.Server
 
+	pollCtx, stopPolling := context.WithCancel(context.Background())
+	defer stopPolling()
+
 	idleConnsClosed := make(chan struct{})
 	go func() {
 		sigchan := make(chan os.Signal, 1)
@@ -77,6 +84,8 @@ func main() {
 			Str("signal", sig.String()).
 			Msg("Stopping in response to signal")
 
+		stopPolling()
+
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
 
@@ -91,20 +100,14 @@ func main() {
 		Str("app_name", appName).
 		Str("version", version).
 		Str("listen_port", cfg.ListenPort).
-		Str("base_url", cfg.BaseURL).
+		Str("base_url", redact.URL(cfg.BaseURL)).
+		Int("instances", len(cfg.Instances)).
 		Msg("Exporter Started.")
 
-	ex, err := exporter.NewSabnzbdExporter(cfg.BaseURL, cfg.ApiKey)
-	if err != nil {
-		log.Fatal().
-			Err(err).
-			Msg("Failed to build SabnzbD Collector.")
-	}
-
 	infoMetricOpts.ConstLabels = prometheus.Labels{
 		"app_name": appName,
 		"version":  version,
-		"base_url": cfg.BaseURL,
+		"base_url": redact.URL(cfg.BaseURL),
 	}
 
 	reg := prometheus.NewPedanticRegistry()
@@ -113,9 +116,54 @@ func main() {
 			infoMetricOpts,
 			func() float64 { return 1 },
 		),
-		ex,
 	)
 
+	var state *exporter.StateStore
+	if cfg.StateFile != "" {
+		state = exporter.LoadStateStore(cfg.StateFile)
+	}
+
+	instances := make([]exporter.Instance, 0, len(cfg.Instances)+1)
+
+	for _, instance := range cfg.Targets() {
+		clientOpts, err := instance.Client.Options()
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("instance", instance.Name).
+				Msg("Failed to configure SabnzbD client.")
+		}
+
+		ex, err := exporter.NewSabnzbdExporter(
+			instance.BaseURL,
+			instance.ApiKey,
+			exporter.WithTargetName(instance.Name),
+			exporter.WithPollInterval(cfg.PollInterval),
+			exporter.WithResultTTL(cfg.ResultTTL),
+			exporter.WithTimeout(cfg.Timeout),
+			exporter.WithQueueJobMetrics(cfg.QueueJobLimit),
+			exporter.WithWarningCategories(cfg.WarningCategories),
+			exporter.WithStateStore(state),
+			exporter.WithResetMode(exporter.ResetMode(cfg.StatsResetMode)),
+			exporter.WithServerGracePeriod(cfg.ServerGracePeriod),
+			exporter.WithClientOptions(clientOpts...),
+			exporter.WithClientOptions(client.WithRetryPolicy(cfg.Retry.Policy())),
+		)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("instance", instance.Name).
+				Msg("Failed to build SabnzbD Collector.")
+		}
+
+		instances = append(instances, exporter.Instance{
+			Exporter: ex,
+			Labels:   instance.Labels,
+		})
+
+		ex.Start(pollCtx)
+	}
+
 	if cfg.GoCollector {
 		reg.MustRegister(collectors.NewGoCollector())
 	}
@@ -124,10 +172,29 @@ func main() {
 		reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 	}
 
+	scrapeTimeout := exporter.ScrapeTimeout{
+		Default: cfg.Timeout,
+		Offset:  cfg.TimeoutOffset,
+	}
+
 	router := http.NewServeMux()
-	router.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	router.Handle("/metrics", exporter.NewMetricsHandler(reg, instances, scrapeTimeout))
 	router.Handle("/healthz", newHealthCheckHandler())
 
+	if len(cfg.Modules) > 0 {
+		router.Handle("/probe", probe.NewHandler(
+			cfg.Modules,
+			scrapeTimeout,
+			exporter.WithResultTTL(cfg.ResultTTL),
+			exporter.WithTimeout(cfg.Timeout),
+			exporter.WithQueueJobMetrics(cfg.QueueJobLimit),
+			exporter.WithWarningCategories(cfg.WarningCategories),
+			exporter.WithResetMode(exporter.ResetMode(cfg.StatsResetMode)),
+			exporter.WithServerGracePeriod(cfg.ServerGracePeriod),
+			exporter.WithClientOptions(client.WithRetryPolicy(cfg.Retry.Policy())),
+		))
+	}
+
 	srv.Addr = fmt.Sprintf(":%s", cfg.ListenPort)
 	srv.Handler = router
 
@@ -136,4 +203,10 @@ func main() {
 	}
 
 	<-idleConnsClosed
+
+	if state != nil {
+		if err := state.Save(); err != nil {
+			log.Error().Err(err).Msg("Failed to persist state")
+		}
+	}
 }
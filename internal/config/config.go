@@ -2,8 +2,16 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+
+	"prometheus-sabnzbd-exporter/internal/redact"
+	"prometheus-sabnzbd-exporter/pkg/client"
+	"prometheus-sabnzbd-exporter/pkg/secret"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
@@ -19,12 +27,425 @@ import (
 var ENV_PREFIX = "SABNZBD_"
 
 type Config struct {
-	BaseURL          string `koanf:"base_url"`
-	ApiKey           string `koanf:"api_key"`
-	ListenPort       string `koanf:"listen_port"`
-	LogLevel         string `koanf:"log_level"`
-	GoCollector      bool   `koanf:"go_collector"`
-	ProcessCollector bool   `koanf:"process_collector"`
+	BaseURL           string            `koanf:"base_url"`
+	ApiKey            string            `koanf:"api_key"`
+	ListenPort        string            `koanf:"listen_port"`
+	LogLevel          string            `koanf:"log_level"`
+	GoCollector       bool              `koanf:"go_collector"`
+	ProcessCollector  bool              `koanf:"process_collector"`
+	PollInterval      time.Duration     `koanf:"poll_interval"`
+	ResultTTL         time.Duration     `koanf:"result_ttl"`
+	Timeout           time.Duration     `koanf:"timeout"`
+	TimeoutOffset     time.Duration     `koanf:"timeout_offset"`
+	QueueJobLimit     int               `koanf:"queue_job_metrics_limit"`
+	WarningCategories map[string]string `koanf:"warning_categories"`
+	StateFile         string            `koanf:"state_file"`
+	StatsResetMode    string            `koanf:"stats_reset_mode"`
+	ServerGracePeriod time.Duration     `koanf:"server_grace_period"`
+	Retry             RetryConfig       `koanf:",squash"`
+	Client            ClientConfig      `koanf:",squash"`
+	Modules           map[string]Module `koanf:"modules"`
+	Instances         []Instance        `koanf:"instances"`
+}
+
+// RetryConfig configures retries of transient SabnzbD errors.
+type RetryConfig struct {
+	MaxAttempts int           `koanf:"retry_max_attempts"`
+	BaseBackoff time.Duration `koanf:"retry_base_backoff"`
+	MaxBackoff  time.Duration `koanf:"retry_max_backoff"`
+	Jitter      float64       `koanf:"retry_jitter"`
+	StatusCodes []int         `koanf:"retry_status_codes"`
+}
+
+func (r RetryConfig) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.MaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&r.BaseBackoff, validation.Min(time.Duration(0))),
+		validation.Field(&r.MaxBackoff, validation.Min(r.BaseBackoff)),
+		validation.Field(&r.Jitter, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&r.StatusCodes, validation.Each(validation.Min(100), validation.Max(599))),
+	)
+}
+
+// Policy returns the client.RetryPolicy configured by r.
+func (r RetryConfig) Policy() client.RetryPolicy {
+	return client.RetryPolicy{
+		MaxAttempts: r.MaxAttempts,
+		BaseBackoff: r.BaseBackoff,
+		MaxBackoff:  r.MaxBackoff,
+		Jitter:      r.Jitter,
+		StatusCodes: r.StatusCodes,
+	}
+}
+
+// ClientConfig configures the connection to a single SabnzbD instance. It's
+// set at the top level for base_url, and per instance & module.
+type ClientConfig struct {
+	ApiKeyFile string     `koanf:"api_key_file"`
+	APIPath    string     `koanf:"api_path"`
+	TLS        TLSConfig  `koanf:",squash"`
+	Auth       AuthConfig `koanf:",squash"`
+}
+
+var apiPathRegexp = regexp.MustCompile("^(auto|/.*)$")
+
+func (c ClientConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.ApiKeyFile, validation.By(validateFile)),
+		validation.Field(&c.APIPath, validation.Match(apiPathRegexp).Error("must be 'auto' or start with /")),
+		validation.Field(&c.TLS),
+		validation.Field(&c.Auth),
+	)
+}
+
+// Options returns the client.Options configured by c, loading any referenced
+// files. Secrets read from files are reloaded whenever the files change, and
+// every secret is registered for redaction.
+func (c ClientConfig) Options() ([]client.Option, error) {
+	opts := []client.Option{client.WithAPIPath(c.APIPath)}
+
+	if c.ApiKeyFile != "" {
+		apiKey, err := secret.FromFile(c.ApiKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, client.WithAPIKey(redact.Secret(apiKey)))
+	}
+
+	if tlsOpts := c.TLS.Options(); !tlsOpts.IsZero() {
+		tlsConfig, err := client.NewTLSConfig(tlsOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, client.WithTLSConfig(tlsConfig))
+	}
+
+	auth, err := c.Auth.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, client.WithAuth(auth))
+
+	return opts, nil
+}
+
+// TLSConfig configures https connections to SabnzbD.
+type TLSConfig struct {
+	CAFile             string `koanf:"tls_ca_file"`
+	CertFile           string `koanf:"tls_cert_file"`
+	KeyFile            string `koanf:"tls_key_file"`
+	ServerName         string `koanf:"tls_server_name"`
+	InsecureSkipVerify bool   `koanf:"tls_insecure_skip_verify"`
+}
+
+func (t TLSConfig) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.CAFile, validation.By(validateFile)),
+		validation.Field(&t.CertFile, validation.When(t.KeyFile != "", validation.Required), validation.By(validateFile)),
+		validation.Field(&t.KeyFile, validation.When(t.CertFile != "", validation.Required), validation.By(validateFile)),
+	)
+}
+
+// Options returns the client.TLSOptions configured by t.
+func (t TLSConfig) Options() client.TLSOptions {
+	return client.TLSOptions{
+		CAFile:             t.CAFile,
+		CertFile:           t.CertFile,
+		KeyFile:            t.KeyFile,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+}
+
+// AuthConfig configures credentials required by a reverse proxy in front of
+// SabnzbD. Every secret can alternatively be read from a file.
+type AuthConfig struct {
+	Username        string            `koanf:"basic_auth_username"`
+	Password        string            `koanf:"basic_auth_password"`
+	PasswordFile    string            `koanf:"basic_auth_password_file"`
+	BearerToken     string            `koanf:"bearer_token"`
+	BearerTokenFile string            `koanf:"bearer_token_file"`
+	Headers         map[string]string `koanf:"headers"`
+	HeaderFiles     map[string]string `koanf:"header_files"`
+}
+
+var headerNameRegexp = regexp.MustCompile("^[a-zA-Z0-9!#$%&'*+.^_`|~-]+$")
+
+func (a AuthConfig) Validate() error {
+	basicAuth := a.Username != "" || a.Password != "" || a.PasswordFile != ""
+	bearerToken := a.BearerToken != "" || a.BearerTokenFile != ""
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Username, validation.When(basicAuth, validation.Required)),
+		validation.Field(&a.Password, validation.When(a.PasswordFile != "", validation.Empty.Error("must not be set with basic_auth_password_file"))),
+		validation.Field(&a.PasswordFile, validation.By(validateFile)),
+		validation.Field(&a.BearerToken,
+			validation.When(a.BearerTokenFile != "", validation.Empty.Error("must not be set with bearer_token_file")),
+			validation.When(basicAuth && bearerToken, validation.Empty.Error("must not be set with basic auth")),
+		),
+		validation.Field(&a.BearerTokenFile,
+			validation.When(basicAuth, validation.Empty.Error("must not be set with basic auth")),
+			validation.By(validateFile),
+		),
+		validation.Field(&a.Headers, validation.By(validateHeaderNames)),
+		validation.Field(&a.HeaderFiles,
+			validation.By(validateHeaderNames),
+			validation.By(a.validateHeaderOverlap),
+			validation.Each(validation.By(validateFile)),
+		),
+	)
+}
+
+func (a AuthConfig) validateHeaderOverlap(interface{}) error {
+	for name := range a.HeaderFiles {
+		if _, ok := a.Headers[name]; ok {
+			return fmt.Errorf("header %q is also set in headers", name)
+		}
+	}
+
+	return nil
+}
+
+func validateHeaderNames(value interface{}) error {
+	headers, _ := value.(map[string]string)
+	for name := range headers {
+		if !headerNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// Load returns the client.Auth configured by a. Secrets read from files are
+// reloaded whenever the files change, and every secret but the plain headers
+// is registered for redaction.
+func (a AuthConfig) Load() (client.Auth, error) {
+	auth := client.Auth{Username: a.Username}
+
+	var err error
+
+	if a.Password != "" || a.PasswordFile != "" {
+		if auth.Password, err = secret.Load(a.Password, a.PasswordFile); err != nil {
+			return client.Auth{}, err
+		}
+
+		auth.Password = redact.Secret(auth.Password)
+	}
+
+	if a.BearerToken != "" || a.BearerTokenFile != "" {
+		if auth.BearerToken, err = secret.Load(a.BearerToken, a.BearerTokenFile); err != nil {
+			return client.Auth{}, err
+		}
+
+		auth.BearerToken = redact.Secret(auth.BearerToken)
+	}
+
+	if len(a.Headers) > 0 || len(a.HeaderFiles) > 0 {
+		auth.Headers = make(map[string]secret.Secret, len(a.Headers)+len(a.HeaderFiles))
+		for name, value := range a.Headers {
+			// Plain headers aren't secret, e.g. X-Forwarded-User, and registering
+			// short values would redact them from every url.
+			auth.Headers[name] = secret.Static(value)
+		}
+
+		for name, path := range a.HeaderFiles {
+			file, err := secret.FromFile(path)
+			if err != nil {
+				return client.Auth{}, err
+			}
+
+			auth.Headers[name] = redact.Secret(file)
+		}
+	}
+
+	return auth, nil
+}
+
+// validateApiKey requires either api_key or api_key_file to be set.
+func validateApiKey(c ClientConfig) []validation.Rule {
+	return []validation.Rule{
+		validation.When(c.ApiKeyFile == "", validation.Required.Error("must be set, or api_key_file")),
+		validation.When(c.ApiKeyFile != "", validation.Empty.Error("must not be set with api_key_file")),
+	}
+}
+
+func validateFile(value interface{}) error {
+	path, _ := value.(string)
+	if path == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("file %q can't be read", path)
+	}
+
+	return nil
+}
+
+func validateParentDir(value interface{}) error {
+	path, _ := value.(string)
+	if path == "" {
+		return nil
+	}
+
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory of %q doesn't exist", path)
+	}
+
+	return nil
+}
+
+// Module is a named set of credentials used by the /probe endpoint to
+// authenticate against the requested target. Targets are regexes matching the
+// whole targets the credentials may be sent to. Any target is allowed when
+// empty.
+type Module struct {
+	ApiKey  string       `koanf:"api_key"`
+	Targets []string     `koanf:"targets"`
+	Client  ClientConfig `koanf:",squash"`
+}
+
+func (m Module) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.ApiKey, validateApiKey(m.Client)...),
+		validation.Field(&m.Targets, validation.Each(validation.By(validateRegex))),
+		validation.Field(&m.Client),
+	)
+}
+
+// RESERVED_LABELS are label names used by the exporter's own metrics, which
+// can't be reused as extra instance labels.
+var RESERVED_LABELS = []interface{}{
+	"target", "server", "folder", "version", "status", "endpoint", "class", "source", "reason",
+	"category", "priority", "nzo_id", "name", "level", "period", "le", "server_name",
+}
+
+var labelNameRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// Instance is a statically configured SabnzbD instance, exported on /metrics
+// with `target` set to Name and Labels added to every metric.
+type Instance struct {
+	Name    string            `koanf:"name"`
+	BaseURL string            `koanf:"base_url"`
+	ApiKey  string            `koanf:"api_key"`
+	Labels  map[string]string `koanf:"labels"`
+	Client  ClientConfig      `koanf:",squash"`
+}
+
+func (i Instance) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Name, validation.Required),
+		validation.Field(&i.BaseURL, validation.Required, is.URL),
+		validation.Field(&i.ApiKey, validateApiKey(i.Client)...),
+		validation.Field(&i.Labels, validation.By(validateLabels)),
+		validation.Field(&i.Client),
+	)
+}
+
+func validateLabels(value interface{}) error {
+	labels, _ := value.(map[string]string)
+	for name := range labels {
+		if !labelNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+
+		// Label names starting with __ are reserved by prometheus.
+		if strings.HasPrefix(name, "__") {
+			return fmt.Errorf("label name %q is reserved", name)
+		}
+
+		if err := validation.Validate(name, validation.NotIn(RESERVED_LABELS...)); err != nil {
+			return fmt.Errorf("label name %q is reserved", name)
+		}
+	}
+
+	return nil
+}
+
+// validateUniqueNames requires the names of all targets to be unique,
+// including the one named after the top level base_url.
+func (c *Config) validateUniqueNames(interface{}) error {
+	targets := c.Targets()
+	seen := make(map[string]bool, len(targets))
+
+	for _, i := range targets {
+		if seen[i.Name] {
+			return fmt.Errorf("duplicate instance name %q", i.Name)
+		}
+
+		seen[i.Name] = true
+	}
+
+	return nil
+}
+
+// RegisterSecrets registers the api keys & the passwords of the base urls of
+// the configured targets & modules for redaction. Secrets read from files are
+// registered when loaded by ClientConfig.Options. Targets passed to /probe are
+// never registered, as anyone able to probe could otherwise redact arbitrary
+// strings from logs & labels.
+func (c *Config) RegisterSecrets() {
+	for _, t := range c.Targets() {
+		redact.Register(t.ApiKey, urlPassword(t.BaseURL))
+	}
+
+	for _, m := range c.Modules {
+		redact.Register(m.ApiKey)
+	}
+}
+
+// urlPassword returns the password of rawURL, which is parsed like the client
+// does, also as host:port.
+func urlPassword(rawURL string) string {
+	for _, raw := range []string{rawURL, "http://" + rawURL} {
+		if u, err := url.Parse(raw); err == nil {
+			if password, ok := u.User.Password(); ok {
+				return password
+			}
+		}
+	}
+
+	return ""
+}
+
+// Targets returns every statically configured instance, including the one
+// configured via the top level base_url & api_key. Labels are padded with
+// empty values so that all instances expose the same label names, which the
+// prometheus registry requires.
+func (c *Config) Targets() []Instance {
+	targets := make([]Instance, 0, len(c.Instances)+1)
+	if c.BaseURL != "" {
+		targets = append(targets, Instance{
+			Name:    redact.URL(c.BaseURL),
+			BaseURL: c.BaseURL,
+			ApiKey:  c.ApiKey,
+			Client:  c.Client,
+		})
+	}
+
+	targets = append(targets, c.Instances...)
+
+	labelNames := make(map[string]struct{})
+	for _, t := range targets {
+		for name := range t.Labels {
+			labelNames[name] = struct{}{}
+		}
+	}
+
+	for i, t := range targets {
+		labels := make(map[string]string, len(labelNames))
+		for name := range labelNames {
+			labels[name] = t.Labels[name]
+		}
+
+		targets[i].Labels = labels
+	}
+
+	return targets
 }
 
 func LoadConfig(appName string, args []string) (*Config, error) {
@@ -38,9 +459,37 @@ func LoadConfig(appName string, args []string) (*Config, error) {
 	f.String("log_level", "info", "log level (debug, info, warn, error)")
 	f.Bool("go_collector", false, "enables go stats exporter")
 	f.Bool("process_collector", false, "enables process stats exporter")
+	f.Duration("poll_interval", 0, "poll sabnzbd in the background at this interval instead of on every scrape (0 to disable)")
+	f.Duration("result_ttl", 0, "serve scrapes arriving within this duration of the last query from its result (0 to disable)")
+	f.Duration("timeout", 10*time.Second, "timeout querying sabnzbd, when prometheus doesn't send its scrape timeout")
+	f.Duration("timeout_offset", 500*time.Millisecond, "safety margin subtracted from prometheus' scrape timeout")
+	f.Int("queue_job_metrics_limit", 0, "export per-job metrics for up to this many jobs at the front of the queue (0 to disable)")
+	f.StringToString("warning_categories", map[string]string{}, "categories of sabnzbd's warnings by regex matching their text, added to or replacing the default ones (name=regex, empty to remove)")
+	f.String("state_file", "", "path to a file the server counters are persisted to, so that they survive restarts (disabled when empty)")
+	f.String("stats_reset_mode", "passthrough", "how server counters are exported when sabnzbd's statistics are reset (passthrough, monotonic)")
+	f.Duration("server_grace_period", 24*time.Hour, "stop exporting news servers missing from sabnzbd's server stats for this long (0 to never stop)")
+	f.Int("retry_max_attempts", 3, "attempts made to query sabnzbd, including the first (1 disables retries)")
+	f.Duration("retry_base_backoff", 100*time.Millisecond, "backoff before the first retry, doubled on every retry")
+	f.Duration("retry_max_backoff", 2*time.Second, "maximum backoff between retries")
+	f.Float64("retry_jitter", 0.2, "fraction of the backoff which is randomized (0-1)")
+	f.IntSlice("retry_status_codes", []int{502, 503, 504}, "http status codes which are retried")
+	f.String("api_path", "", "path of sabnzbd's api below base_url, or auto to discover it (default /sabnzbd/api)")
+	f.String("tls_ca_file", "", "path to a pem encoded CA certificate used to verify sabnzbd")
+	f.String("tls_cert_file", "", "path to a pem encoded client certificate presented to sabnzbd")
+	f.String("tls_key_file", "", "path to the pem encoded key of tls_cert_file")
+	f.String("tls_server_name", "", "server name used to verify sabnzbd's certificate, instead of base_url's host")
+	f.Bool("tls_insecure_skip_verify", false, "don't verify sabnzbd's certificate")
+	f.String("basic_auth_username", "", "username sent to sabnzbd's reverse proxy via basic auth")
+	f.String("basic_auth_password", "", "password sent to sabnzbd's reverse proxy via basic auth")
+	f.String("basic_auth_password_file", "", "path to a file containing basic_auth_password, reloaded when it changes")
+	f.String("bearer_token", "", "bearer token sent to sabnzbd's reverse proxy")
+	f.String("bearer_token_file", "", "path to a file containing bearer_token, reloaded when it changes")
+	f.StringToString("headers", map[string]string{}, "extra http headers sent to sabnzbd (name=value)")
+	f.StringToString("header_files", map[string]string{}, "extra http headers sent to sabnzbd, read from files reloaded when they change (name=path)")
 	f.String("listen_port", "8080", "port to listen on")
 	f.String("base_url", "", "base url of sabnzbd")
 	f.String("api_key", "", "api key of sabnzbd")
+	f.String("api_key_file", "", "path to a file containing api_key, reloaded when it changes")
 
 	err := f.Parse(args)
 	if err != nil {
@@ -52,6 +501,20 @@ func LoadConfig(appName string, args []string) (*Config, error) {
 		"listen_port":       "8080",
 		"go_collector":      false,
 		"process_collector": false,
+		"poll_interval":     "0s",
+		"result_ttl":        "0s",
+		"timeout":           "10s",
+		"timeout_offset":    "500ms",
+
+		"queue_job_metrics_limit": 0,
+		"stats_reset_mode":        "passthrough",
+		"server_grace_period":     "24h",
+
+		"retry_max_attempts": 3,
+		"retry_base_backoff": "100ms",
+		"retry_max_backoff":  "2s",
+		"retry_jitter":       0.2,
+		"retry_status_codes": []int{502, 503, 504},
 	}, "."), nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error loading default config: %w", err)
@@ -86,10 +549,47 @@ func LoadConfig(appName string, args []string) (*Config, error) {
 }
 
 func (c *Config) Validate() error {
+	// base_url & api_key may be omitted when instances are configured, or when
+	// the exporter is only used via /probe.
+	optional := (len(c.Modules) > 0 || len(c.Instances) > 0) && c.BaseURL == ""
+
 	return validation.ValidateStruct(c,
-		validation.Field(&c.BaseURL, validation.Required, is.URL),
-		validation.Field(&c.ApiKey, validation.Required),
+		validation.Field(&c.BaseURL, validation.When(!optional, validation.Required), is.URL),
+		validation.Field(&c.ApiKey, validation.When(!optional, validateApiKey(c.Client)...)),
 		validation.Field(&c.ListenPort, validation.Required, is.Port),
 		validation.Field(&c.LogLevel, validation.Required, validation.In("debug", "info", "warn", "error")),
+		validation.Field(&c.PollInterval, validation.Min(time.Duration(0))),
+		validation.Field(&c.ResultTTL, validation.Min(time.Duration(0))),
+		validation.Field(&c.Timeout, validation.Required, validation.Min(time.Duration(0))),
+		validation.Field(&c.TimeoutOffset, validation.Min(time.Duration(0))),
+		validation.Field(&c.QueueJobLimit, validation.Min(0)),
+		validation.Field(&c.WarningCategories, validation.By(validateRegexes)),
+		validation.Field(&c.StateFile, validation.By(validateParentDir)),
+		validation.Field(&c.StatsResetMode, validation.Required, validation.In("passthrough", "monotonic")),
+		validation.Field(&c.ServerGracePeriod, validation.Min(time.Duration(0))),
+		validation.Field(&c.Retry),
+		validation.Field(&c.Client),
+		validation.Field(&c.Modules),
+		validation.Field(&c.Instances, validation.By(c.validateUniqueNames)),
 	)
 }
+
+func validateRegex(value interface{}) error {
+	expr, _ := value.(string)
+	if _, err := regexp.Compile(expr); err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+
+	return nil
+}
+
+func validateRegexes(value interface{}) error {
+	regexes, _ := value.(map[string]string)
+	for name, expr := range regexes {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid regex for %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,15 @@
 package exporter
 
 import (
-	"prometheus-sabnzbd-exporter/internal/models"
+	"prometheus-sabnzbd-exporter/pkg/models"
 	"sync"
+	"time"
 )
 
+// DEFAULT_SERVER_GRACE_PERIOD is how long servers missing from SabnzbD's
+// server stats are kept, e.g. while they're briefly removed and added back.
+var DEFAULT_SERVER_GRACE_PERIOD = 24 * time.Hour
+
 type ServerStats interface {
 	Update(stat models.ServerStat) ServerStats
 	GetTotal() int
@@ -12,21 +17,40 @@ type ServerStats interface {
 	GetArticlesSuccess() int
 }
 
+// ResetMode selects how counters are exported after SabnzbD's statistics went
+// backwards, e.g. because they were reset.
+type ResetMode string
+
+const (
+	// RESET_MODE_PASSTHROUGH exports SabnzbD's statistics as they are, so the
+	// counters drop like counters of a restarted process.
+	RESET_MODE_PASSTHROUGH ResetMode = "passthrough"
+	// RESET_MODE_MONOTONIC adds the statistics lost by a reset as offsets, so
+	// the counters never decrease.
+	RESET_MODE_MONOTONIC ResetMode = "monotonic"
+)
+
 type serverStatCache struct {
 	total                     int
+	totalOffset               int
 	articlesTriedHistorical   int
 	articlesTriedToday        int
 	articlesSuccessHistorical int
 	articlesSuccessToday      int
 	todayKey                  string
+	lastSeen                  time.Time
 }
 
+// Update accumulates the article counts of stat. When the day changed, or the
+// server is seen for the first time, every day from the last day parsed up to
+// the new one is added from the daily stats, so that neither the end of the
+// last day nor days missed while the exporter was down are lost.
 func (s serverStatCache) Update(stat models.ServerStat) ServerStats {
 	s.total = stat.Total
 
 	if stat.DayParsed != s.todayKey {
-		s.articlesTriedHistorical += s.articlesTriedToday
-		s.articlesSuccessHistorical += s.articlesSuccessToday
+		s.articlesTriedHistorical += articlesSince(stat.ArticlesTriedDaily, s.todayKey, stat.DayParsed, s.articlesTriedToday)
+		s.articlesSuccessHistorical += articlesSince(stat.ArticlesSuccessDaily, s.todayKey, stat.DayParsed, s.articlesSuccessToday)
 		s.articlesTriedToday = 0
 		s.articlesSuccessToday = 0
 		s.todayKey = stat.DayParsed
@@ -38,8 +62,83 @@ func (s serverStatCache) Update(stat models.ServerStat) ServerStats {
 	return s
 }
 
+// regressed returns whether stat went backwards, compared to the cached stats.
+func (s serverStatCache) regressed(stat models.ServerStat) bool {
+	if stat.Total < s.total || stat.DayParsed < s.todayKey {
+		return true
+	}
+
+	return stat.DayParsed == s.todayKey &&
+		(stat.ArticlesTried < s.articlesTriedToday || stat.ArticlesSuccess < s.articlesSuccessToday)
+}
+
+// reset returns the cache restarting to accumulate stats after stat went
+// backwards. Stats are then accumulated like those of a server seen for the
+// first time, so in RESET_MODE_MONOTONIC the articles which the backfill of
+// stat's daily stats adds back aren't part of the offsets.
+func (s serverStatCache) reset(mode ResetMode, stat models.ServerStat) serverStatCache {
+	if mode != RESET_MODE_MONOTONIC {
+		return serverStatCache{}
+	}
+
+	sameDay := stat.DayParsed == s.todayKey
+
+	return serverStatCache{
+		totalOffset: s.GetTotal(),
+		articlesTriedHistorical: lostArticles(
+			s.GetArticlesTried(), stat.ArticlesTriedDaily, s.todayKey,
+			sameDay && stat.ArticlesTried >= s.articlesTriedToday, s.articlesTriedToday,
+		),
+		articlesSuccessHistorical: lostArticles(
+			s.GetArticlesSuccess(), stat.ArticlesSuccessDaily, s.todayKey,
+			sameDay && stat.ArticlesSuccess >= s.articlesSuccessToday, s.articlesSuccessToday,
+		),
+	}
+}
+
+// lostArticles returns the articles of counted which SabnzbD no longer
+// reports: counted less the days before today still in daily, and less
+// today's count when it was kept.
+func lostArticles(counted int, daily map[string]int, today string, keptToday bool, todayCount int) int {
+	lost := counted
+	if keptToday {
+		lost -= todayCount
+	}
+
+	for day, count := range daily {
+		if day < today {
+			lost -= count
+		}
+	}
+
+	if lost < 0 {
+		return 0
+	}
+
+	return lost
+}
+
+// articlesSince sums the daily article counts from the day from, up to but
+// excluding the day to. Days are formatted YYYY-MM-DD, so they sort
+// chronologically. The last count seen of from is used when daily doesn't
+// include it, or when the day went backwards.
+func articlesSince(daily map[string]int, from, to string, last int) int {
+	sum := 0
+	if _, ok := daily[from]; !ok || to < from {
+		sum += last
+	}
+
+	for day, count := range daily {
+		if day >= from && day < to {
+			sum += count
+		}
+	}
+
+	return sum
+}
+
 func (s serverStatCache) GetTotal() int {
-	return s.total
+	return s.totalOffset + s.total
 }
 
 func (s serverStatCache) GetArticlesTried() int {
@@ -51,21 +150,41 @@ func (s serverStatCache) GetArticlesSuccess() int {
 }
 
 type ServersStatsCache struct {
-	lock    sync.RWMutex
-	Total   int
-	Servers map[string]serverStatCache
+	lock        sync.RWMutex
+	mode        ResetMode
+	gracePeriod time.Duration // 0 keeps servers forever
+	now         func() time.Time
+	Total       int
+	TotalOffset int // Total lost by resets, in RESET_MODE_MONOTONIC
+	Servers     map[string]serverStatCache
 }
 
 func NewServersStatsCache() *ServersStatsCache {
 	return &ServersStatsCache{
-		Servers: make(map[string]serverStatCache),
+		mode:        RESET_MODE_PASSTHROUGH,
+		gracePeriod: DEFAULT_SERVER_GRACE_PERIOD,
+		now:         time.Now,
+		Servers:     make(map[string]serverStatCache),
 	}
 }
 
-func (c *ServersStatsCache) Update(stats models.ServerStats) {
+// Update caches stats. It returns the names of the servers whose stats went
+// backwards, and "" when the overall total did, followed by the names of the
+// servers evicted after missing from stats for longer than the grace period.
+func (c *ServersStatsCache) Update(stats models.ServerStats) (resets []string, evicted []string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	now := c.now()
+
+	if stats.Total < c.Total {
+		resets = append(resets, "")
+
+		if c.mode == RESET_MODE_MONOTONIC {
+			c.TotalOffset += c.Total
+		}
+	}
+
 	c.Total = stats.Total
 
 	for name, srv := range stats.Servers {
@@ -74,15 +193,33 @@ func (c *ServersStatsCache) Update(stats models.ServerStats) {
 			toCache = cached
 		}
 
-		c.Servers[name] = toCache.Update(srv).(serverStatCache)
+		if toCache.regressed(srv) {
+			resets = append(resets, name)
+			toCache = toCache.reset(c.mode, srv)
+		}
+
+		toCache = toCache.Update(srv).(serverStatCache)
+		toCache.lastSeen = now
+		c.Servers[name] = toCache
 	}
+
+	if c.gracePeriod > 0 {
+		for name, s := range c.Servers {
+			if now.Sub(s.lastSeen) > c.gracePeriod {
+				evicted = append(evicted, name)
+				delete(c.Servers, name)
+			}
+		}
+	}
+
+	return resets, evicted
 }
 
 func (c *ServersStatsCache) GetTotal() int {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	return c.Total
+	return c.TotalOffset + c.Total
 }
 
 func (c *ServersStatsCache) GetServerMap() map[string]ServerStats {
@@ -96,3 +233,54 @@ func (c *ServersStatsCache) GetServerMap() map[string]ServerStats {
 
 	return ret
 }
+
+// State returns the state of the counters, to be persisted.
+func (c *ServersStatsCache) State() TargetState {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	ret := TargetState{
+		Total:   TotalState{Total: c.Total, Offset: c.TotalOffset},
+		Servers: make(map[string]ServerState, len(c.Servers)),
+	}
+
+	for name, s := range c.Servers {
+		ret.Servers[name] = ServerState{
+			Total:                     s.total,
+			TotalOffset:               s.totalOffset,
+			Day:                       s.todayKey,
+			ArticlesTriedHistorical:   s.articlesTriedHistorical,
+			ArticlesTriedToday:        s.articlesTriedToday,
+			ArticlesSuccessHistorical: s.articlesSuccessHistorical,
+			ArticlesSuccessToday:      s.articlesSuccessToday,
+		}
+	}
+
+	return ret
+}
+
+// Restore sets the counters to their persisted state. The grace period of
+// restored servers starts over, as the time they were last seen isn't
+// persisted.
+func (c *ServersStatsCache) Restore(state TargetState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+
+	c.Total = state.Total.Total
+	c.TotalOffset = state.Total.Offset
+
+	for name, s := range state.Servers {
+		c.Servers[name] = serverStatCache{
+			total:                     s.Total,
+			totalOffset:               s.TotalOffset,
+			todayKey:                  s.Day,
+			articlesTriedHistorical:   s.ArticlesTriedHistorical,
+			articlesTriedToday:        s.ArticlesTriedToday,
+			articlesSuccessHistorical: s.ArticlesSuccessHistorical,
+			articlesSuccessToday:      s.ArticlesSuccessToday,
+			lastSeen:                  now,
+		}
+	}
+}
@@ -1,8 +1,9 @@
 package exporter
 
 import (
-	"prometheus-sabnzbd-exporter/internal/models"
+	"prometheus-sabnzbd-exporter/pkg/models"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -242,3 +243,186 @@ func TestGetServerMap_ReturnsCopy(t *testing.T) {
 	require.NotEqual(cServer.GetArticlesTried(), sServer.GetArticlesTried())
 	require.NotEqual(cServer.GetArticlesSuccess(), sServer.GetArticlesSuccess())
 }
+
+func TestUpdateServerStatsCache_BackfillsDailyStats(t *testing.T) {
+	require := require.New(t)
+	cache := NewServersStatsCache()
+	cache.Update(*models.NewServerStatsFromResponse(models.ServerStatsResponse{
+		Servers: map[string]models.ServerStatResponse{
+			"server1": {
+				ArticlesTried:   map[string]int{"2020-01-01": 10, "2020-01-02": 20, "2020-01-03": 30},
+				ArticlesSuccess: map[string]int{"2020-01-01": 9, "2020-01-02": 19, "2020-01-03": 29},
+			},
+		},
+	}))
+
+	server1 := cache.GetServerMap()["server1"]
+	require.Equal(60, server1.GetArticlesTried())
+	require.Equal(57, server1.GetArticlesSuccess())
+}
+
+func TestUpdateServerStatsCache_MultiDayGap(t *testing.T) {
+	require := require.New(t)
+	cache := NewServersStatsCache()
+	cache.Update(*models.NewServerStatsFromResponse(models.ServerStatsResponse{
+		Servers: map[string]models.ServerStatResponse{
+			"server1": {
+				ArticlesTried:   map[string]int{"2020-01-01": 10, "2020-01-02": 20},
+				ArticlesSuccess: map[string]int{"2020-01-01": 10, "2020-01-02": 20},
+			},
+		},
+	}))
+
+	// 2020-01-02 ended with 25 articles, and the exporter missed 2020-01-03
+	cache.Update(*models.NewServerStatsFromResponse(models.ServerStatsResponse{
+		Servers: map[string]models.ServerStatResponse{
+			"server1": {
+				ArticlesTried:   map[string]int{"2020-01-01": 10, "2020-01-02": 25, "2020-01-03": 40, "2020-01-04": 5},
+				ArticlesSuccess: map[string]int{"2020-01-01": 10, "2020-01-02": 25, "2020-01-03": 40, "2020-01-04": 5},
+			},
+		},
+	}))
+
+	server1 := cache.GetServerMap()["server1"]
+	require.Equal(80, server1.GetArticlesTried())
+	require.Equal(80, server1.GetArticlesSuccess())
+
+	// Days pruned from the daily stats keep their last seen count
+	cache.Update(*models.NewServerStatsFromResponse(models.ServerStatsResponse{
+		Servers: map[string]models.ServerStatResponse{
+			"server1": {
+				ArticlesTried:   map[string]int{"2020-01-05": 1},
+				ArticlesSuccess: map[string]int{"2020-01-05": 1},
+			},
+		},
+	}))
+
+	server1 = cache.GetServerMap()["server1"]
+	require.Equal(81, server1.GetArticlesTried())
+}
+
+func TestUpdateServerStatsCache_Reset(t *testing.T) {
+	parameters := []struct {
+		mode            ResetMode
+		total           int
+		serverTotal     int
+		articlesTried   int
+		articlesSuccess int
+	}{
+		{RESET_MODE_PASSTHROUGH, 10, 5, 25, 23},
+		{RESET_MODE_MONOTONIC, 160, 55, 45, 42},
+	}
+
+	for _, tt := range parameters {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			require := require.New(t)
+			cache := NewServersStatsCache()
+			cache.mode = tt.mode
+
+			resets, _ := cache.Update(models.ServerStats{
+				Total: 150,
+				Servers: map[string]models.ServerStat{
+					"server1": {
+						Total:                50,
+						ArticlesTried:        20,
+						ArticlesSuccess:      19,
+						ArticlesTriedDaily:   map[string]int{"2020-01-01": 10, "2020-01-02": 20},
+						ArticlesSuccessDaily: map[string]int{"2020-01-01": 9, "2020-01-02": 19},
+						DayParsed:            "2020-01-02",
+					},
+					"server2": {Total: 50, ArticlesTried: 20, DayParsed: "2020-01-02"},
+					"server3": {
+						Total:              50,
+						ArticlesTried:      20,
+						ArticlesTriedDaily: map[string]int{"2020-01-01": 10, "2020-01-02": 20},
+						DayParsed:          "2020-01-02",
+					},
+				},
+			})
+			require.Empty(resets)
+
+			// SabnzbD's statistics of server1 were cleared and partly
+			// downloaded again, only the total of server3 was cleared
+			resets, _ = cache.Update(models.ServerStats{
+				Total: 10,
+				Servers: map[string]models.ServerStat{
+					"server1": {
+						Total:                5,
+						ArticlesTried:        15,
+						ArticlesSuccess:      14,
+						ArticlesTriedDaily:   map[string]int{"2020-01-01": 10, "2020-01-02": 15},
+						ArticlesSuccessDaily: map[string]int{"2020-01-01": 9, "2020-01-02": 14},
+						DayParsed:            "2020-01-02",
+					},
+					"server2": {Total: 60, ArticlesTried: 25, DayParsed: "2020-01-02"},
+					"server3": {
+						Total:              5,
+						ArticlesTried:      20,
+						ArticlesTriedDaily: map[string]int{"2020-01-01": 10, "2020-01-02": 20},
+						DayParsed:          "2020-01-02",
+					},
+				},
+			})
+			require.ElementsMatch([]string{"", "server1", "server3"}, resets)
+
+			require.Equal(tt.total, cache.GetTotal())
+
+			server1 := cache.GetServerMap()["server1"]
+			require.Equal(tt.serverTotal, server1.GetTotal())
+			require.Equal(tt.articlesTried, server1.GetArticlesTried())
+			require.Equal(tt.articlesSuccess, server1.GetArticlesSuccess())
+
+			server2 := cache.GetServerMap()["server2"]
+			require.Equal(60, server2.GetTotal())
+			require.Equal(25, server2.GetArticlesTried())
+
+			// The daily stats kept by SabnzbD aren't counted twice
+			server3 := cache.GetServerMap()["server3"]
+			require.Equal(30, server3.GetArticlesTried())
+		})
+	}
+}
+
+func TestUpdateServerStatsCache_EvictsMissingServers(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	cache := NewServersStatsCache()
+	cache.gracePeriod = time.Hour
+	cache.now = func() time.Time { return now }
+
+	_, evicted := cache.Update(models.ServerStats{
+		Servers: map[string]models.ServerStat{
+			"server1": {Total: 50, DayParsed: "2020-01-02"},
+			"server2": {Total: 50, DayParsed: "2020-01-02"},
+		},
+	})
+	require.Empty(evicted)
+
+	// server2 was removed from SabnzbD, and is kept for the grace period
+	now = now.Add(time.Hour)
+	_, evicted = cache.Update(models.ServerStats{
+		Servers: map[string]models.ServerStat{
+			"server1": {Total: 60, DayParsed: "2020-01-02"},
+		},
+	})
+	require.Empty(evicted)
+	require.Len(cache.GetServerMap(), 2)
+
+	now = now.Add(time.Minute)
+	_, evicted = cache.Update(models.ServerStats{
+		Servers: map[string]models.ServerStat{
+			"server1": {Total: 70, DayParsed: "2020-01-02"},
+		},
+	})
+	require.Equal([]string{"server2"}, evicted)
+	require.Len(cache.GetServerMap(), 1)
+	require.NotContains(cache.State().Servers, "server2")
+
+	// Without a grace period, servers are kept forever
+	cache.gracePeriod = 0
+	now = now.Add(24 * time.Hour)
+	_, evicted = cache.Update(models.ServerStats{})
+	require.Empty(evicted)
+	require.Len(cache.GetServerMap(), 1)
+}
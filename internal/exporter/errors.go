@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"prometheus-sabnzbd-exporter/pkg/client"
+)
+
+// errorClass buckets query errors into a small set of classes, which are
+// exported as the class label of sabnzbd_endpoint_errors_total.
+func errorClass(err error) string {
+	var (
+		statusErr    *client.StatusError
+		apiErr       *client.APIError
+		syntaxErr    *json.SyntaxError
+		unmarshalErr *json.UnmarshalTypeError
+		netErr       net.Error
+	)
+
+	switch {
+	case errors.Is(err, client.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, client.ErrAuth):
+		return "auth"
+	case errors.Is(err, client.ErrNotAllowed):
+		return "not_allowed"
+	case errors.Is(err, client.ErrUnknownMode):
+		return "unknown_mode"
+	case errors.As(err, &apiErr):
+		return "api"
+	case errors.Is(err, client.ErrNoAPIPath):
+		return "discovery"
+	case errors.As(err, &statusErr):
+		return "http_status"
+	case errors.Is(err, client.ErrResponseTooLarge):
+		return "too_large"
+	case errors.As(err, &syntaxErr), errors.As(err, &unmarshalErr):
+		return "decode"
+	case errors.As(err, &netErr):
+		return "connection"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"prometheus-sabnzbd-exporter/pkg/client"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClass(t *testing.T) {
+	parameters := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"timeout", fmt.Errorf("%w: slow", client.ErrTimeout), "timeout"},
+		{"deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), "timeout"},
+		{"canceled", fmt.Errorf("wrapped: %w", context.Canceled), "canceled"},
+		{"status", fmt.Errorf("wrapped: %w", &client.StatusError{Code: 503}), "http_status"},
+		{"unauthorized", fmt.Errorf("wrapped: %w", &client.StatusError{Code: 401}), "auth"},
+		{"forbidden", fmt.Errorf("wrapped: %w", &client.StatusError{Code: 403}), "not_allowed"},
+		{"bad api key", &client.APIError{Mode: "queue", Message: "API Key Incorrect", Err: client.ErrAuth}, "auth"},
+		{"not allowed", &client.APIError{Mode: "queue", Message: "Access denied", Err: client.ErrNotAllowed}, "not_allowed"},
+		{"unknown mode", &client.APIError{Mode: "nope", Message: "not implemented", Err: client.ErrUnknownMode}, "unknown_mode"},
+		{"unrecognized api error", &client.APIError{Mode: "queue", Message: "something broke"}, "api"},
+		{"discovery", fmt.Errorf("%w (/sabnzbd/api: client error: 404)", client.ErrNoAPIPath), "discovery"},
+		{"syntax", fmt.Errorf("wrapped: %w", &json.SyntaxError{}), "decode"},
+		{"unmarshal", fmt.Errorf("wrapped: %w", &json.UnmarshalTypeError{}), "decode"},
+		{"too large", fmt.Errorf("wrapped: %w", client.ErrResponseTooLarge), "too_large"},
+		{"connection", &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: errors.New("refused")}}, "connection"},
+		{"other", errors.New("something else"), "other"},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, errorClass(tt.err))
+		})
+	}
+}
@@ -1,19 +1,36 @@
 package exporter
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"prometheus-sabnzbd-exporter/internal/client"
-	"prometheus-sabnzbd-exporter/internal/models"
+	"prometheus-sabnzbd-exporter/internal/redact"
+	"prometheus-sabnzbd-exporter/pkg/client"
+	"prometheus-sabnzbd-exporter/pkg/models"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 var METRIC_PREFIX = "sabnzbd"
 
+// DEFAULT_TIMEOUT matches prometheus' default scrape timeout.
+var DEFAULT_TIMEOUT = 10 * time.Second
+
+// QUEUE_JOB_LABELS are the labels of the per-job queue metrics.
+var QUEUE_JOB_LABELS = []string{"target", "nzo_id", "name", "category", "priority"}
+
+// QUEUE_JOB_NAME_MAX_LENGTH is the length job names are truncated to in the
+// name label.
+var QUEUE_JOB_NAME_MAX_LENGTH = 64
+
 var (
 	downloadedBytes = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "downloaded_bytes"),
@@ -21,12 +38,24 @@ var (
 		[]string{"target"},
 		nil,
 	)
+	downloadedBytesPeriod = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "downloaded_bytes_period"),
+		"Bytes Downloaded by SABnzbd in the current period (day, week, month)",
+		[]string{"target", "period"},
+		nil,
+	)
 	serverDownloadedBytes = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "server_downloaded_bytes"),
 		"Total Bytes Downloaded from UseNet Server",
 		[]string{"target", "server"},
 		nil,
 	)
+	serverDownloadedBytesPeriod = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "server_downloaded_bytes_period"),
+		"Bytes Downloaded from UseNet Server in the current period (day, week, month)",
+		[]string{"target", "server", "period"},
+		nil,
+	)
 	serverArticlesTotal = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "server_articles_total"),
 		"Total Articles Attempted to download from UseNet Server",
@@ -39,6 +68,12 @@ var (
 		[]string{"target", "server"},
 		nil,
 	)
+	trackedServers = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "tracked_servers"),
+		"Number of UseNet Servers whose counters are exported, including those in their grace period after missing from SabnzbD",
+		[]string{"target"},
+		nil,
+	)
 	info = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "info"),
 		"Info about the target SabnzbD instance",
@@ -105,6 +140,18 @@ var (
 		[]string{"target"},
 		nil,
 	)
+	speedLimitRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "speed_limit_ratio"),
+		"Speed Limit of the SabnzbD instance as a fraction of its configured line speed",
+		[]string{"target"},
+		nil,
+	)
+	speedLimitBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "speed_limit_bytes_per_second"),
+		"Speed Limit of the SabnzbD instance in Bytes per Second",
+		[]string{"target"},
+		nil,
+	)
 	bytesRemaining = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "remaining_bytes"),
 		"Total Bytes Remaining to Download by the SabnzbD instance",
@@ -123,6 +170,54 @@ var (
 		[]string{"target"},
 		nil,
 	)
+	queueItems = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_items"),
+		"Number of Items in the SabnzbD instance's queue by status, category and priority",
+		[]string{"target", "status", "category", "priority"},
+		nil,
+	)
+	queueBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_bytes"),
+		"Total Bytes of the Items in the SabnzbD instance's queue by status and category",
+		[]string{"target", "status", "category"},
+		nil,
+	)
+	queueRemainingBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_remaining_bytes"),
+		"Bytes Remaining to Download of the Items in the SabnzbD instance's queue by status and category",
+		[]string{"target", "status", "category"},
+		nil,
+	)
+	queueJobProgress = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_job_progress_ratio"),
+		"Fraction of the job in the SabnzbD instance's queue downloaded (0-1)",
+		QUEUE_JOB_LABELS,
+		nil,
+	)
+	queueJobRemainingBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_job_remaining_bytes"),
+		"Bytes Remaining to Download of the job in the SabnzbD instance's queue",
+		QUEUE_JOB_LABELS,
+		nil,
+	)
+	queueJobETA = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_job_eta_seconds"),
+		"Estimated Time Remaining to Download the job in the SabnzbD instance's queue",
+		QUEUE_JOB_LABELS,
+		nil,
+	)
+	queueJobPosition = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_job_position"),
+		"Position of the job in the SabnzbD instance's queue (0 based)",
+		QUEUE_JOB_LABELS,
+		nil,
+	)
+	queueJobsDropped = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_jobs_dropped"),
+		"Jobs in the SabnzbD instance's queue currently left out of the per-job metrics by their limit",
+		[]string{"target"},
+		nil,
+	)
 	status = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "status"),
 		"Status of the SabnzbD instance's queue (0=Unknown, 1=Idle, 2=Paused, 3=Downloading)",
@@ -135,6 +230,66 @@ var (
 		[]string{"target"},
 		nil,
 	)
+	loadAverage1 = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "load1"),
+		"1m load average of the SabnzbD host",
+		[]string{"target"},
+		nil,
+	)
+	loadAverage5 = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "load5"),
+		"5m load average of the SabnzbD host",
+		[]string{"target"},
+		nil,
+	)
+	loadAverage15 = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "load15"),
+		"15m load average of the SabnzbD host",
+		[]string{"target"},
+		nil,
+	)
+	pystone = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "pystone_score"),
+		"Pystone CPU benchmark score of the SabnzbD host",
+		[]string{"target"},
+		nil,
+	)
+	dirWriteSpeed = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "dir_write_speed_bytes_per_second"),
+		"Write speed of the SabnzbD folder, as last measured by SabnzbD",
+		[]string{"target", "folder"},
+		nil,
+	)
+	internetBandwidth = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "internet_bandwidth_bytes_per_second"),
+		"Internet bandwidth of the SabnzbD host, as last measured by SabnzbD",
+		[]string{"target"},
+		nil,
+	)
+	serverActiveConnections = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "server_active_connections"),
+		"Open connections to the UseNet Server",
+		[]string{"target", "server_name"},
+		nil,
+	)
+	serverMaxConnections = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "server_max_connections"),
+		"Connections configured for the UseNet Server",
+		[]string{"target", "server_name"},
+		nil,
+	)
+	serverEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "server_enabled"),
+		"Is the UseNet Server enabled (1) or disabled (0)",
+		[]string{"target", "server_name"},
+		nil,
+	)
+	serverError = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "server_error"),
+		"Does the UseNet Server report an error, by category of the error",
+		[]string{"target", "server_name", "reason"},
+		nil,
+	)
 	warnings = prometheus.NewDesc(
 		prometheus.BuildFQName(METRIC_PREFIX, "", "warnings"),
 		"Total Warnings in the SabnzbD instance's queue",
@@ -159,6 +314,48 @@ var (
 		[]string{"target"},
 		nil,
 	)
+	historyQueryDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "history_query_duration_seconds"),
+		"Duration querying the history endpoint of SabnzbD",
+		[]string{"target"},
+		nil,
+	)
+	fullStatusQueryDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "fullstatus_query_duration_seconds"),
+		"Duration querying the fullstatus endpoint of SabnzbD",
+		[]string{"target"},
+		nil,
+	)
+	warningsQueryDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "warnings_query_duration_seconds"),
+		"Duration querying the warnings endpoint of SabnzbD",
+		[]string{"target"},
+		nil,
+	)
+	lastPollTimestamp = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "last_poll_timestamp_seconds"),
+		"Unix timestamp of the last completed background poll of SabnzbD",
+		[]string{"target"},
+		nil,
+	)
+	snapshotAge = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "snapshot_age_seconds"),
+		"Age of the polled SabnzbD stats served by this scrape",
+		[]string{"target"},
+		nil,
+	)
+	up = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "up"),
+		"Could the SabnzbD instance be queried (1 if any endpoint responded successfully)",
+		[]string{"target"},
+		nil,
+	)
+	endpointUp = prometheus.NewDesc(
+		prometheus.BuildFQName(METRIC_PREFIX, "", "endpoint_up"),
+		"Was the last query of the SabnzbD API endpoint successful",
+		[]string{"target", "endpoint"},
+		nil,
+	)
 )
 
 func boolToFloat(b bool) float64 {
@@ -170,38 +367,390 @@ func boolToFloat(b bool) float64 {
 }
 
 type SabnzbdExporter struct {
-	baseURL string
-	cache   *ServersStatsCache
-	client  *client.SabnzbdClient
+	target       string // value of the target label, defaults to the base url
+	cache        *ServersStatsCache
+	state        *StateStore // persists the cache, if set
+	history      *historyTracker
+	historyLimit int // number of latest history jobs queried
+	warnings     *warningsTracker
+	categories   map[string]string // overrides of DEFAULT_WARNING_CATEGORIES
+	jobLimit     int               // number of jobs exported with per-job metrics, 0 disables them
+	client       *client.SabnzbdClient
+	pollInterval time.Duration // 0 disables background polling
+	resultTTL    time.Duration // 0 disables reusing results between scrapes
+	timeout      time.Duration // timeout of polls & scrapes without a context
+	clientOpts   []client.Option
+	scrapes      *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	retries      *prometheus.CounterVec
+	resets       *prometheus.CounterVec
+
+	group    singleflight.Group
+	lock     sync.RWMutex
+	snapshot *snapshot // latest fetched snapshot
+}
+
+// snapshot holds the stats gathered by querying SabnzbD once. Endpoints are
+// queried independently, so the stats of an endpoint are only set when its
+// query succeeded.
+type snapshot struct {
+	time              time.Time
+	duration          time.Duration
+	queueResult       endpointResult
+	queueStats        *models.QueueStats
+	serverStatsResult endpointResult
+	serverStats       *models.ServerStats
+	historyResult     endpointResult
+	warningsResult    endpointResult
+	fullStatusResult  endpointResult
+	fullStatus        *models.FullStatus
+}
+
+// newFailedSnapshot returns a snapshot in which every endpoint failed with err.
+func newFailedSnapshot(err error) *snapshot {
+	return &snapshot{
+		time:              time.Now(),
+		queueResult:       endpointResult{err: err},
+		serverStatsResult: endpointResult{err: err},
+		historyResult:     endpointResult{err: err},
+		warningsResult:    endpointResult{err: err},
+		fullStatusResult:  endpointResult{err: err},
+	}
+}
+
+// endpointResult is the outcome of querying a single SabnzbD API endpoint.
+type endpointResult struct {
+	duration time.Duration
+	err      error
+}
+
+// results returns the endpoint results of the snapshot, keyed by endpoint.
+func (s *snapshot) results() map[string]endpointResult {
+	return map[string]endpointResult{
+		"queue":        s.queueResult,
+		"server_stats": s.serverStatsResult,
+		"history":      s.historyResult,
+		"warnings":     s.warningsResult,
+		"fullstatus":   s.fullStatusResult,
+	}
+}
+
+type Option func(*SabnzbdExporter)
+
+// WithTargetName overrides the target label, which defaults to the base url.
+func WithTargetName(name string) Option {
+	return func(e *SabnzbdExporter) {
+		e.target = name
+	}
+}
+
+// WithPollInterval makes Start poll SabnzbD in the background every interval,
+// and Collect serve the latest snapshot instead of querying SabnzbD itself.
+func WithPollInterval(interval time.Duration) Option {
+	return func(e *SabnzbdExporter) {
+		e.pollInterval = interval
+	}
+}
+
+// WithResultTTL makes scrapes within ttl of the last fetch reuse its result
+// instead of querying SabnzbD again.
+func WithResultTTL(ttl time.Duration) Option {
+	return func(e *SabnzbdExporter) {
+		e.resultTTL = ttl
+	}
+}
+
+// WithTimeout sets the timeout of background polls, and of scrapes collected
+// without a context. Defaults to DEFAULT_TIMEOUT.
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *SabnzbdExporter) {
+		e.timeout = timeout
+	}
+}
+
+// WithHistoryLimit sets the number of latest history jobs queried, which
+// defaults to DEFAULT_HISTORY_LIMIT.
+func WithHistoryLimit(limit int) Option {
+	return func(e *SabnzbdExporter) {
+		e.historyLimit = limit
+	}
+}
+
+// WithWarningCategories adds categories of warnings, or replaces those of
+// DEFAULT_WARNING_CATEGORIES, by name. Categories set to an empty regex are
+// removed.
+func WithWarningCategories(categories map[string]string) Option {
+	return func(e *SabnzbdExporter) {
+		e.categories = categories
+	}
+}
+
+// WithQueueJobMetrics exports per-job metrics for the first limit jobs in the
+// queue. Jobs further back are counted as dropped. 0 disables them.
+func WithQueueJobMetrics(limit int) Option {
+	return func(e *SabnzbdExporter) {
+		e.jobLimit = limit
+	}
+}
+
+// WithResetMode sets how counters are exported after SabnzbD's statistics went
+// backwards. Defaults to RESET_MODE_PASSTHROUGH.
+func WithResetMode(mode ResetMode) Option {
+	return func(e *SabnzbdExporter) {
+		e.cache.mode = mode
+	}
 }
 
-func NewSabnzbdExporter(baseURL string, apiKey string) (*SabnzbdExporter, error) {
-	client, err := client.NewSabnzbdClient(baseURL, apiKey)
+// WithServerGracePeriod sets how long servers missing from SabnzbD's server
+// stats are still exported, before their counters are dropped. 0 keeps them
+// forever. Defaults to DEFAULT_SERVER_GRACE_PERIOD.
+func WithServerGracePeriod(period time.Duration) Option {
+	return func(e *SabnzbdExporter) {
+		e.cache.gracePeriod = period
+	}
+}
+
+// WithStateStore restores the server counters from store, and persists them
+// to it whenever they change.
+func WithStateStore(store *StateStore) Option {
+	return func(e *SabnzbdExporter) {
+		e.state = store
+	}
+}
+
+// WithClientOptions passes opts to the client used to query SabnzbD.
+func WithClientOptions(opts ...client.Option) Option {
+	return func(e *SabnzbdExporter) {
+		e.clientOpts = append(e.clientOpts, opts...)
+	}
+}
+
+func NewSabnzbdExporter(baseURL string, apiKey string, opts ...Option) (*SabnzbdExporter, error) {
+	e := &SabnzbdExporter{
+		target:       redact.URL(baseURL),
+		cache:        NewServersStatsCache(),
+		history:      newHistoryTracker(),
+		historyLimit: DEFAULT_HISTORY_LIMIT,
+		timeout:      DEFAULT_TIMEOUT,
+		scrapes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "scrapes_total",
+				Help:      "Total scrapes of the SabnzbD instance by source of the served stats (fetched, coalesced, cached, poll)",
+			},
+			[]string{"target", "source"},
+		),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "endpoint_errors_total",
+				Help:      "Total failed queries of SabnzbD API endpoints by class of error",
+			},
+			[]string{"target", "endpoint", "class"},
+		),
+		retries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "client_retries_total",
+				Help:      "Total retried queries of SabnzbD API endpoints by reason",
+			},
+			[]string{"target", "endpoint", "reason"},
+		),
+		resets: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "stats_resets_total",
+				Help:      "Total times the SabnzbD instance's statistics went backwards, by server (empty for the overall total)",
+			},
+			[]string{"target", "server"},
+		),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.state != nil {
+		e.cache.Restore(e.state.Get(e.target))
+	}
+
+	categories, err := NewWarningCategories(e.categories)
+	if err != nil {
+		return nil, err
+	}
+
+	e.warnings = newWarningsTracker(categories)
+
+	client, err := client.NewSabnzbdClient(baseURL, apiKey, append(e.clientOpts, client.WithRetryHook(e.onRetry))...)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to build client: %w", err)
 	}
 
-	return &SabnzbdExporter{
-		baseURL: baseURL,
-		cache:   NewServersStatsCache(),
-		client:  client,
-	}, nil
+	e.client = client
+
+	return e, nil
 }
 
-func (s *SabnzbdExporter) getQueueStats() (*models.QueueStats, error) {
-	resp, err := s.client.Get("queue")
-	if err != nil {
-		return nil, fmt.Errorf("Failed to get queue stats: %w", err)
+func (e *SabnzbdExporter) onRetry(endpoint, reason string) {
+	e.retries.WithLabelValues(e.target, endpoint, reason).Inc()
+	log.Debug().
+		Str("target", e.target).
+		Str("endpoint", endpoint).
+		Str("reason", reason).
+		Msg("Retrying query")
+}
+
+// countResets counts the servers whose stats went backwards, initializing the
+// counters of every server in stats and deleting those of evicted servers.
+func (e *SabnzbdExporter) countResets(stats models.ServerStats, resets []string, evicted []string) {
+	e.resets.WithLabelValues(e.target, "")
+
+	for name := range stats.Servers {
+		e.resets.WithLabelValues(e.target, name)
 	}
 
-	var queueResponse models.QueueResponse
+	for _, name := range resets {
+		e.resets.WithLabelValues(e.target, name).Inc()
+		log.Warn().
+			Str("target", e.target).
+			Str("server", name).
+			Str("mode", string(e.cache.mode)).
+			Msg("SabnzbD statistics went backwards")
+	}
+
+	for _, name := range evicted {
+		e.resets.DeleteLabelValues(e.target, name)
+		log.Info().
+			Str("target", e.target).
+			Str("server", name).
+			Msg("Stopped exporting server missing from SabnzbD")
+	}
+}
+
+// saveState persists the cache to the state store, if any. Failing to write it
+// doesn't fail the query.
+func (e *SabnzbdExporter) saveState() {
+	if e.state == nil {
+		return
+	}
+
+	if err := e.state.Update(e.target, e.cache.State()); err != nil {
+		log.Warn().
+			Err(err).
+			Str("target", e.target).
+			Msg("Failed to persist state")
+	}
+}
+
+// Start polls SabnzbD in the background until ctx is done. Without a poll
+// interval, it only discovers SabnzbD's api path ahead of the first scrape
+// when configured to.
+func (e *SabnzbdExporter) Start(ctx context.Context) {
+	if e.pollInterval <= 0 {
+		go e.discover(ctx)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(e.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			e.poll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (e *SabnzbdExporter) discover(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	if err := e.client.Discover(ctx); err != nil {
+		log.Warn().
+			Err(err).
+			Str("target", e.target).
+			Msg("Failed to discover api path, retrying on the next scrape")
+	}
+}
+
+func (e *SabnzbdExporter) poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	e.refresh(ctx)
+}
+
+// refresh fetches a new snapshot, sharing a single in-flight fetch between
+// concurrent callers. leader is only true for the caller that ran the fetch.
+// Callers joining an in-flight fetch stop waiting for it once their ctx is
+// done, while the leader's fetch is cancelled by its ctx.
+func (e *SabnzbdExporter) refresh(ctx context.Context) (*snapshot, bool) {
+	var leader atomic.Bool
+
+	ch := e.group.DoChan("fetch", func() (interface{}, error) {
+		leader.Store(true)
+		snap := e.fetch(ctx)
+
+		e.lock.Lock()
+		e.snapshot = snap
+		e.lock.Unlock()
+
+		return snap, nil
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val.(*snapshot), leader.Load()
+	case <-ctx.Done():
+		if leader.Load() {
+			res := <-ch
+			return res.Val.(*snapshot), true
+		}
+
+		return newFailedSnapshot(fmt.Errorf("%w: %s", client.ErrTimeout, ctx.Err())), false
+	}
+}
+
+func (e *SabnzbdExporter) latestSnapshot() *snapshot {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.snapshot
+}
+
+// getSnapshot returns the stats a scrape should be served from, and their source.
+func (e *SabnzbdExporter) getSnapshot(ctx context.Context) (*snapshot, string) {
+	if snap := e.latestSnapshot(); snap != nil {
+		if e.pollInterval > 0 {
+			return snap, "poll"
+		}
+
+		if e.resultTTL > 0 && time.Since(snap.time) < e.resultTTL {
+			return snap, "cached"
+		}
+	}
+
+	snap, leader := e.refresh(ctx)
+	if !leader {
+		return snap, "coalesced"
+	}
 
-	err = json.NewDecoder(resp.Body).Decode(&queueResponse)
+	return snap, "fetched"
+}
+
+func (s *SabnzbdExporter) getQueueStats(ctx context.Context) (*models.QueueStats, error) {
+	queueResponse, err := s.client.Queue(ctx, client.QueueOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("Failed to decode queue stats JSON: %w", err)
+		return nil, fmt.Errorf("Failed to get queue stats: %w", err)
 	}
 
-	queueStats, err := models.NewQueueStatsFromResponse(queueResponse)
+	queueStats, err := models.NewQueueStatsFromResponse(*queueResponse)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to parse queue Stats: %w", err)
 	}
@@ -209,24 +758,54 @@ func (s *SabnzbdExporter) getQueueStats() (*models.QueueStats, error) {
 	return &queueStats, nil
 }
 
-func (s *SabnzbdExporter) getServerStats() (*models.ServerStats, error) {
-	resp, err := s.client.Get("server_stats")
+func (s *SabnzbdExporter) getServerStats(ctx context.Context) (*models.ServerStats, error) {
+	statsResponse, err := s.client.ServerStats(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get server stats: %w", err)
 	}
 
-	var statsResponse models.ServerStatsResponse
+	return models.NewServerStatsFromResponse(*statsResponse), nil
+}
+
+func (s *SabnzbdExporter) getHistoryStats(ctx context.Context) (*models.HistoryStats, error) {
+	historyResponse, err := s.client.History(ctx, client.HistoryOptions{Limit: s.historyLimit})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get history: %w", err)
+	}
+
+	historyStats := models.NewHistoryStatsFromResponse(*historyResponse)
+
+	return &historyStats, nil
+}
+
+func (s *SabnzbdExporter) getWarnings(ctx context.Context) ([]models.Warning, error) {
+	warningsResponse, err := s.client.Warnings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get warnings: %w", err)
+	}
+
+	return models.NewWarningsFromResponse(*warningsResponse), nil
+}
+
+// getFullStatus queries fullstatus without the dashboard's ip address & dns
+// checks, and without measuring performance, which take several seconds.
+func (s *SabnzbdExporter) getFullStatus(ctx context.Context) (*models.FullStatus, error) {
+	statusResponse, err := s.client.FullStatus(ctx, client.FullStatusOptions{SkipDashboard: true})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get full status: %w", err)
+	}
 
-	err = json.NewDecoder(resp.Body).Decode(&statsResponse)
+	fullStatus, err := models.NewFullStatusFromResponse(*statusResponse)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to decode server stats JSON: %w", err)
+		return nil, fmt.Errorf("Failed to parse full status: %w", err)
 	}
 
-	return models.NewServerStatsFromResponse(statsResponse), nil
+	return &fullStatus, nil
 }
 
 func (e *SabnzbdExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- downloadedBytes
+	ch <- downloadedBytesPeriod
 	ch <- info
 	ch <- paused
 	ch <- pausedAll
@@ -238,147 +817,505 @@ func (e *SabnzbdExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cachedArticles
 	ch <- cachedBytes
 	ch <- speed
+	ch <- speedLimitRatio
+	ch <- speedLimitBytes
 	ch <- bytesRemaining
 	ch <- bytesTotal
 	ch <- queueLength
+	ch <- queueItems
+	ch <- queueBytes
+	ch <- queueRemainingBytes
+	ch <- queueJobProgress
+	ch <- queueJobRemainingBytes
+	ch <- queueJobETA
+	ch <- queueJobPosition
+	ch <- queueJobsDropped
 	ch <- status
 	ch <- timeEstimate
 	ch <- serverDownloadedBytes
+	ch <- serverDownloadedBytesPeriod
 	ch <- serverArticlesTotal
 	ch <- serverArticlesSuccess
+	ch <- trackedServers
+	ch <- loadAverage1
+	ch <- loadAverage5
+	ch <- loadAverage15
+	ch <- pystone
+	ch <- dirWriteSpeed
+	ch <- internetBandwidth
+	ch <- serverActiveConnections
+	ch <- serverMaxConnections
+	ch <- serverEnabled
+	ch <- serverError
 	ch <- warnings
 	ch <- scrapeDuration
 	ch <- queueQueryDuration
 	ch <- serverStatsQueryDuration
+	ch <- historyQueryDuration
+	ch <- warningsQueryDuration
+	ch <- fullStatusQueryDuration
+	ch <- lastPollTimestamp
+	ch <- snapshotAge
+	ch <- up
+	ch <- endpointUp
+
+	e.scrapes.Describe(ch)
+	e.errors.Describe(ch)
+	e.retries.Describe(ch)
+	e.history.Describe(ch)
+	e.warnings.Describe(ch)
+	e.resets.Describe(ch)
 }
 
-func (e *SabnzbdExporter) Collect(ch chan<- prometheus.Metric) {
+// query runs fn, timing & logging the query of a single endpoint.
+func (e *SabnzbdExporter) query(endpoint string, fn func() error) endpointResult {
 	start := time.Now()
-	defer func() { //nolint:wsl
-		ch <- prometheus.MustNewConstMetric(scrapeDuration, prometheus.GaugeValue, time.Since(start).Seconds(), e.baseURL)
+
+	err := fn()
+	if err != nil {
+		class := errorClass(err)
+		e.errors.WithLabelValues(e.target, endpoint, class).Inc()
+		log.Err(err).
+			Str("target", e.target).
+			Str("endpoint", endpoint).
+			Str("class", class).
+			Msg("Failed to query endpoint")
+	}
+
+	return endpointResult{
+		duration: time.Since(start),
+		err:      err,
+	}
+}
+
+// fetch queries all SabnzbD endpoints concurrently, cancelling outstanding
+// queries once ctx is done.
+func (e *SabnzbdExporter) fetch(ctx context.Context) *snapshot {
+	snap := &snapshot{}
+	start := time.Now()
+
+	var wg sync.WaitGroup
+
+	wg.Add(5)
+
+	go func() {
+		defer wg.Done()
+
+		snap.queueResult = e.query("queue", func() error {
+			var err error
+			snap.queueStats, err = e.getQueueStats(ctx)
+
+			return err
+		})
 	}()
 
-	queueStats := &models.QueueStats{}
-	serverStats := &models.ServerStats{}
+	go func() {
+		defer wg.Done()
 
-	g := new(errgroup.Group)
+		snap.serverStatsResult = e.query("server_stats", func() error {
+			var err error
+			snap.serverStats, err = e.getServerStats(ctx)
+			if err != nil {
+				return err
+			}
 
-	g.Go(func() error {
-		qStart := time.Now()
-		defer func() { //nolint:wsl
-			ch <- prometheus.MustNewConstMetric(
-				queueQueryDuration, prometheus.GaugeValue, time.Since(qStart).Seconds(), e.baseURL)
-		}()
-
-		var err error
-		queueStats, err = e.getQueueStats()
-		if err != nil {
-			log.Err(err).Msg("Failed to get queue stats")
-			return fmt.Errorf("failed to get queue stats: %w", err)
-		}
-		return nil
-	})
+			resets, evicted := e.cache.Update(*snap.serverStats)
+			e.countResets(*snap.serverStats, resets, evicted)
+			e.saveState()
 
-	g.Go(func() error {
-		sStart := time.Now()
-		defer func() { //nolint:wsl
-			ch <- prometheus.MustNewConstMetric(
-				serverStatsQueryDuration, prometheus.GaugeValue, time.Since(sStart).Seconds(), e.baseURL)
-		}()
-
-		var err error
-		serverStats, err = e.getServerStats()
-		if err != nil {
-			log.Err(err).
-				Msg("Failed to get server stats")
-			return fmt.Errorf("failed to get server stats: %w", err)
-		}
+			return nil
+		})
+	}()
 
-		e.cache.Update(*serverStats)
+	go func() {
+		defer wg.Done()
 
-		return nil
-	})
+		snap.historyResult = e.query("history", func() error {
+			historyStats, err := e.getHistoryStats(ctx)
+			if err != nil {
+				return err
+			}
+
+			e.history.Update(e.target, *historyStats)
 
-	if err := g.Wait(); err != nil {
-		log.Err(err).Msg("Failed to get stats")
-		ch <- prometheus.NewInvalidMetric(
-			prometheus.NewDesc("sabnzbd_exporter_error", "Error getting stats", nil, prometheus.Labels{"target": e.baseURL}),
-			err,
+			return nil
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		snap.warningsResult = e.query("warnings", func() error {
+			warnings, err := e.getWarnings(ctx)
+			if err != nil {
+				return err
+			}
+
+			e.warnings.Update(e.target, warnings)
+
+			return nil
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		snap.fullStatusResult = e.query("fullstatus", func() error {
+			var err error
+			snap.fullStatus, err = e.getFullStatus(ctx)
+
+			return err
+		})
+	}()
+
+	wg.Wait()
+
+	snap.time = time.Now()
+	snap.duration = snap.time.Sub(start)
+
+	return snap
+}
+
+// Collect implements prometheus.Collector, querying SabnzbD with the default timeout.
+func (e *SabnzbdExporter) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	e.CollectWithContext(ctx, ch)
+}
+
+// CollectWithContext collects metrics, cancelling queries of SabnzbD once ctx is done.
+func (e *SabnzbdExporter) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	snap, source := e.getSnapshot(ctx)
+
+	e.scrapes.WithLabelValues(e.target, source).Inc()
+	e.scrapes.Collect(ch)
+	e.errors.Collect(ch)
+	e.retries.Collect(ch)
+	e.history.Collect(ch)
+	e.warnings.Collect(ch)
+	e.resets.Collect(ch)
+
+	if e.pollInterval > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			lastPollTimestamp, prometheus.GaugeValue, float64(snap.time.UnixNano())/1e9, e.target,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			snapshotAge, prometheus.GaugeValue, time.Since(snap.time).Seconds(), e.target,
 		)
+	}
 
-		return
+	ch <- prometheus.MustNewConstMetric(scrapeDuration, prometheus.GaugeValue, snap.duration.Seconds(), e.target)
+	ch <- prometheus.MustNewConstMetric(
+		queueQueryDuration, prometheus.GaugeValue, snap.queueResult.duration.Seconds(), e.target)
+	ch <- prometheus.MustNewConstMetric(
+		serverStatsQueryDuration, prometheus.GaugeValue, snap.serverStatsResult.duration.Seconds(), e.target)
+	ch <- prometheus.MustNewConstMetric(
+		historyQueryDuration, prometheus.GaugeValue, snap.historyResult.duration.Seconds(), e.target)
+	ch <- prometheus.MustNewConstMetric(
+		warningsQueryDuration, prometheus.GaugeValue, snap.warningsResult.duration.Seconds(), e.target)
+	ch <- prometheus.MustNewConstMetric(
+		fullStatusQueryDuration, prometheus.GaugeValue, snap.fullStatusResult.duration.Seconds(), e.target)
+
+	anyUp := false
+
+	for endpoint, result := range snap.results() {
+		anyUp = anyUp || result.err == nil
+		ch <- prometheus.MustNewConstMetric(
+			endpointUp, prometheus.GaugeValue, boolToFloat(result.err == nil), e.target, endpoint,
+		)
 	}
 
+	ch <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, boolToFloat(anyUp), e.target)
+
+	if snap.queueStats != nil {
+		e.collectQueueStats(ch, snap.queueStats)
+	}
+
+	if snap.serverStats != nil {
+		e.collectServerStats(ch, snap.serverStats)
+	}
+
+	if snap.fullStatus != nil {
+		e.collectFullStatus(ch, snap.fullStatus)
+	}
+}
+
+func (e *SabnzbdExporter) collectQueueStats(ch chan<- prometheus.Metric, queueStats *models.QueueStats) {
 	ch <- prometheus.MustNewConstMetric(
-		downloadedBytes, prometheus.CounterValue, float64(e.cache.GetTotal()), e.baseURL,
+		info, prometheus.GaugeValue, 1, e.target, queueStats.Version, queueStats.Status.String(),
 	)
 	ch <- prometheus.MustNewConstMetric(
-		info, prometheus.GaugeValue, 1, e.baseURL, queueStats.Version, queueStats.Status.String(),
+		paused, prometheus.GaugeValue, boolToFloat(queueStats.Paused), e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		paused, prometheus.GaugeValue, boolToFloat(queueStats.Paused), e.baseURL,
+		pausedAll, prometheus.GaugeValue, boolToFloat(queueStats.PausedAll), e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		pausedAll, prometheus.GaugeValue, boolToFloat(queueStats.PausedAll), e.baseURL,
+		pauseDuration, prometheus.GaugeValue, queueStats.PauseDuration.Seconds(), e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		pauseDuration, prometheus.GaugeValue, queueStats.PauseDuration.Seconds(), e.baseURL,
+		diskUsed, prometheus.GaugeValue, queueStats.DownloadDirDiskspaceUsed, e.target, "download",
 	)
 	ch <- prometheus.MustNewConstMetric(
-		diskUsed, prometheus.GaugeValue, queueStats.DownloadDirDiskspaceUsed, e.baseURL, "download",
+		diskUsed, prometheus.GaugeValue, queueStats.CompletedDirDiskspaceUsed, e.target, "complete",
 	)
 	ch <- prometheus.MustNewConstMetric(
-		diskUsed, prometheus.GaugeValue, queueStats.CompletedDirDiskspaceUsed, e.baseURL, "complete",
+		diskTotal, prometheus.GaugeValue, queueStats.DownloadDirDiskspaceTotal, e.target, "download",
 	)
 	ch <- prometheus.MustNewConstMetric(
-		diskTotal, prometheus.GaugeValue, queueStats.DownloadDirDiskspaceTotal, e.baseURL, "download",
+		diskTotal, prometheus.GaugeValue, queueStats.CompletedDirDiskspaceTotal, e.target, "complete",
 	)
 	ch <- prometheus.MustNewConstMetric(
-		diskTotal, prometheus.GaugeValue, queueStats.CompletedDirDiskspaceTotal, e.baseURL, "complete",
+		remainingQuota, prometheus.GaugeValue, queueStats.RemainingQuota, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		remainingQuota, prometheus.GaugeValue, queueStats.RemainingQuota, e.baseURL,
+		quota, prometheus.GaugeValue, queueStats.Quota, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		quota, prometheus.GaugeValue, queueStats.Quota, e.baseURL,
+		cachedArticles, prometheus.GaugeValue, queueStats.CacheArt, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		cachedArticles, prometheus.GaugeValue, queueStats.CacheArt, e.baseURL,
+		cachedBytes, prometheus.GaugeValue, queueStats.CacheSize, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		cachedBytes, prometheus.GaugeValue, queueStats.CacheSize, e.baseURL,
+		speed, prometheus.GaugeValue, queueStats.Speed, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		speed, prometheus.GaugeValue, queueStats.Speed, e.baseURL,
+		speedLimitRatio, prometheus.GaugeValue, queueStats.SpeedLimit/100, e.target,
 	)
+
+	// Without a limit, SabnzbD reports no absolute limit rather than 0 B/s
+	if queueStats.HaveSpeedLimitAbs {
+		ch <- prometheus.MustNewConstMetric(
+			speedLimitBytes, prometheus.GaugeValue, queueStats.SpeedLimitAbs, e.target,
+		)
+	}
+
 	ch <- prometheus.MustNewConstMetric(
-		bytesRemaining, prometheus.GaugeValue, queueStats.RemainingSize, e.baseURL,
+		bytesRemaining, prometheus.GaugeValue, queueStats.RemainingSize, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		bytesTotal, prometheus.GaugeValue, queueStats.Size, e.baseURL,
+		bytesTotal, prometheus.GaugeValue, queueStats.Size, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		queueLength, prometheus.GaugeValue, queueStats.ItemsInQueue, e.baseURL,
+		queueLength, prometheus.GaugeValue, queueStats.ItemsInQueue, e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		status, prometheus.GaugeValue, queueStats.Status.Float64(), e.baseURL,
+		status, prometheus.GaugeValue, queueStats.Status.Float64(), e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		timeEstimate, prometheus.GaugeValue, queueStats.TimeEstimate.Seconds(), e.baseURL,
+		timeEstimate, prometheus.GaugeValue, queueStats.TimeEstimate.Seconds(), e.target,
 	)
 	ch <- prometheus.MustNewConstMetric(
-		warnings, prometheus.GaugeValue, queueStats.HaveWarnings, e.baseURL,
+		warnings, prometheus.GaugeValue, queueStats.HaveWarnings, e.target,
 	)
 
-	for name, stats := range e.cache.GetServerMap() {
+	e.collectQueueSlots(ch, queueStats.Slots)
+
+	if e.jobLimit > 0 {
+		e.collectQueueJobs(ch, queueStats.Slots)
+	}
+}
+
+// collectQueueJobs exports the first jobs in the queue individually, up to the
+// job limit.
+func (e *SabnzbdExporter) collectQueueJobs(ch chan<- prometheus.Metric, slots []models.QueueSlot) {
+	jobs := make([]models.QueueSlot, len(slots))
+	copy(jobs, slots)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Index < jobs[j].Index
+	})
+
+	dropped := 0
+
+	if len(jobs) > e.jobLimit {
+		dropped = len(jobs) - e.jobLimit
+		jobs = jobs[:e.jobLimit]
+	}
+
+	ch <- prometheus.MustNewConstMetric(queueJobsDropped, prometheus.GaugeValue, float64(dropped), e.target)
+
+	for _, job := range jobs {
+		labels := []string{e.target, job.ID, sanitizeJobName(job.Name), job.Category, job.Priority}
+
+		ch <- prometheus.MustNewConstMetric(queueJobProgress, prometheus.GaugeValue, job.Progress, labels...)
+		ch <- prometheus.MustNewConstMetric(queueJobRemainingBytes, prometheus.GaugeValue, job.RemainingSize, labels...)
+		ch <- prometheus.MustNewConstMetric(queueJobETA, prometheus.GaugeValue, job.TimeEstimate.Seconds(), labels...)
+		ch <- prometheus.MustNewConstMetric(queueJobPosition, prometheus.GaugeValue, float64(job.Index), labels...)
+	}
+}
+
+// sanitizeJobName makes a job name fit for a label value, by replacing control
+// characters & invalid utf-8, and truncating it to QUEUE_JOB_NAME_MAX_LENGTH.
+func sanitizeJobName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return '_'
+		}
+
+		return r
+	}, strings.ToValidUTF8(name, "_"))
+
+	if utf8.RuneCountInString(name) > QUEUE_JOB_NAME_MAX_LENGTH {
+		name = string([]rune(name)[:QUEUE_JOB_NAME_MAX_LENGTH])
+	}
+
+	return name
+}
+
+// collectQueueSlots aggregates the jobs in the queue, as per-job series would
+// be too many.
+func (e *SabnzbdExporter) collectQueueSlots(ch chan<- prometheus.Metric, slots []models.QueueSlot) {
+	type itemsKey struct{ status, category, priority string }
+
+	type bytesKey struct{ status, category string }
+
+	items := make(map[itemsKey]float64)
+	size := make(map[bytesKey]float64)
+	remaining := make(map[bytesKey]float64)
+
+	for _, slot := range slots {
+		items[itemsKey{slot.Status, slot.Category, slot.Priority}]++
+		size[bytesKey{slot.Status, slot.Category}] += slot.Size
+		remaining[bytesKey{slot.Status, slot.Category}] += slot.RemainingSize
+	}
+
+	for k, v := range items {
+		ch <- prometheus.MustNewConstMetric(
+			queueItems, prometheus.GaugeValue, v, e.target, k.status, k.category, k.priority,
+		)
+	}
+
+	for k, v := range size {
+		ch <- prometheus.MustNewConstMetric(
+			queueBytes, prometheus.GaugeValue, v, e.target, k.status, k.category,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			queueRemainingBytes, prometheus.GaugeValue, remaining[k], e.target, k.status, k.category,
+		)
+	}
+}
+
+func (e *SabnzbdExporter) collectServerStats(ch chan<- prometheus.Metric, serverStats *models.ServerStats) {
+	ch <- prometheus.MustNewConstMetric(
+		downloadedBytes, prometheus.CounterValue, float64(e.cache.GetTotal()), e.target,
+	)
+
+	for period, bytes := range periodBytes(serverStats.Day, serverStats.Week, serverStats.Month) {
+		ch <- prometheus.MustNewConstMetric(
+			downloadedBytesPeriod, prometheus.GaugeValue, float64(bytes), e.target, period,
+		)
+	}
+
+	// The periods of the latest stats, as the cache doesn't track them
+	for name, stats := range serverStats.Servers {
+		for period, bytes := range periodBytes(stats.Day, stats.Week, stats.Month) {
+			ch <- prometheus.MustNewConstMetric(
+				serverDownloadedBytesPeriod, prometheus.GaugeValue, float64(bytes), e.target, name, period,
+			)
+		}
+	}
+
+	servers := e.cache.GetServerMap()
+
+	ch <- prometheus.MustNewConstMetric(
+		trackedServers, prometheus.GaugeValue, float64(len(servers)), e.target,
+	)
+
+	for name, stats := range servers {
+		ch <- prometheus.MustNewConstMetric(
+			serverDownloadedBytes, prometheus.CounterValue, float64(stats.GetTotal()), e.target, name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			serverArticlesTotal, prometheus.CounterValue, float64(stats.GetArticlesTried()), e.target, name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			serverArticlesSuccess, prometheus.CounterValue, float64(stats.GetArticlesSuccess()), e.target, name,
+		)
+	}
+}
+
+func (e *SabnzbdExporter) collectFullStatus(ch chan<- prometheus.Metric, fullStatus *models.FullStatus) {
+	if len(fullStatus.LoadAverages) == 3 {
+		ch <- prometheus.MustNewConstMetric(
+			loadAverage1, prometheus.GaugeValue, fullStatus.LoadAverages[0], e.target,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			loadAverage5, prometheus.GaugeValue, fullStatus.LoadAverages[1], e.target,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			loadAverage15, prometheus.GaugeValue, fullStatus.LoadAverages[2], e.target,
+		)
+	}
+
+	// SabnzbD reports 0 for performance measures it hasn't measured yet
+	if fullStatus.Pystone > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			pystone, prometheus.GaugeValue, fullStatus.Pystone, e.target,
+		)
+	}
+
+	if fullStatus.DownloadDirSpeed > 0 {
 		ch <- prometheus.MustNewConstMetric(
-			serverDownloadedBytes, prometheus.CounterValue, float64(stats.GetTotal()), e.baseURL, name,
+			dirWriteSpeed, prometheus.GaugeValue, fullStatus.DownloadDirSpeed, e.target, "download",
 		)
+	}
+
+	if fullStatus.CompleteDirSpeed > 0 {
 		ch <- prometheus.MustNewConstMetric(
-			serverArticlesTotal, prometheus.CounterValue, float64(stats.GetArticlesTried()), e.baseURL, name,
+			dirWriteSpeed, prometheus.GaugeValue, fullStatus.CompleteDirSpeed, e.target, "complete",
 		)
+	}
+
+	if fullStatus.InternetBandwidth > 0 {
 		ch <- prometheus.MustNewConstMetric(
-			serverArticlesSuccess, prometheus.CounterValue, float64(stats.GetArticlesSuccess()), e.baseURL, name,
+			internetBandwidth, prometheus.GaugeValue, fullStatus.InternetBandwidth, e.target,
 		)
 	}
+
+	// fullstatus only names servers by their display name, which unlike their
+	// id, i.e. the server label of the server_stats metrics, isn't unique.
+	seen := make(map[string]struct{}, len(fullStatus.Servers))
+
+	for _, server := range fullStatus.Servers {
+		if _, ok := seen[server.Name]; ok {
+			log.Debug().
+				Str("target", e.target).
+				Str("server_name", server.Name).
+				Msg("Skipping server sharing its display name with another server")
+
+			continue
+		}
+
+		seen[server.Name] = struct{}{}
+
+		ch <- prometheus.MustNewConstMetric(
+			serverActiveConnections, prometheus.GaugeValue, float64(server.ActiveConnections), e.target, server.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			serverMaxConnections, prometheus.GaugeValue, float64(server.MaxConnections), e.target, server.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			serverEnabled, prometheus.GaugeValue, boolToFloat(server.Enabled), e.target, server.Name,
+		)
+
+		// Errors are free text naming the server, so they're classified like
+		// warnings to keep the reason label bounded.
+		if server.Error != "" {
+			ch <- prometheus.MustNewConstMetric(
+				serverError, prometheus.GaugeValue, 1, e.target, server.Name, e.warnings.categories.Classify(server.Error),
+			)
+		}
+	}
+}
+
+// periodBytes returns the bytes downloaded in the current day, week & month,
+// keyed by the period label.
+func periodBytes(day, week, month int) map[string]int {
+	return map[string]int{
+		"day":   day,
+		"week":  week,
+		"month": month,
+	}
 }
@@ -1,14 +1,23 @@
 package exporter
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"prometheus-sabnzbd-exporter/pkg/client"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,6 +33,12 @@ func newTestServer(t *testing.T, fn func(http.ResponseWriter, *http.Request)) (*
 	require.NoError(t, err)
 	serverStats, err := os.ReadFile("test_fixtures/server_stats.json")
 	require.NoError(t, err)
+	history, err := os.ReadFile("test_fixtures/history.json")
+	require.NoError(t, err)
+	warnings, err := os.ReadFile("test_fixtures/warnings.json")
+	require.NoError(t, err)
+	fullStatus, err := os.ReadFile("test_fixtures/fullstatus.json")
+	require.NoError(t, err)
 
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fn(w, r)
@@ -37,6 +52,18 @@ func newTestServer(t *testing.T, fn func(http.ResponseWriter, *http.Request)) (*
 			w.WriteHeader(http.StatusOK)
 			_, err := w.Write(serverStats)
 			require.NoError(t, err)
+		case "history":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(history)
+			require.NoError(t, err)
+		case "warnings":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(warnings)
+			require.NoError(t, err)
+		case "fullstatus":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(fullStatus)
+			require.NoError(t, err)
 		}
 	})), nil
 }
@@ -55,7 +82,7 @@ func TestCollect(t *testing.T) {
 	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
 	require.NoError(err)
 
-	assert.GreaterOrEqual(t, 29, testutil.CollectAndCount(collector))
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(collector), 29)
 
 	b, err := os.ReadFile("test_fixtures/expected_metrics.txt")
 	require.NoError(err)
@@ -66,9 +93,12 @@ func TestCollect(t *testing.T) {
 	require.NotPanics(func() {
 		err = testutil.CollectAndCompare(collector, f,
 			"sabnzbd_downloaded_bytes",
+			"sabnzbd_downloaded_bytes_period",
+			"sabnzbd_server_downloaded_bytes_period",
 			"sabnzbd_server_downloaded_bytes",
 			"sabnzbd_server_articles_total",
 			"sabnzbd_server_articles_success",
+			"sabnzbd_tracked_servers",
 			"sabnzbd_info",
 			"sabnzbd_paused",
 			"sabnzbd_paused_all",
@@ -80,13 +110,31 @@ func TestCollect(t *testing.T) {
 			"sabnzbd_article_cache_articles",
 			"sabnzbd_article_cache_bytes",
 			"sabnzbd_speed_bps",
+			"sabnzbd_speed_limit_ratio",
+			"sabnzbd_speed_limit_bytes_per_second",
 			"sabnzbd_remaining_bytes",
 			"sabnzbd_total_bytes",
 			"sabnzbd_queue_size",
+			"sabnzbd_stats_resets_total",
 			"sabnzbd_status",
 			"sabnzbd_time_estimate_seconds",
 			"sabnzbd_queue_length",
+			"sabnzbd_queue_items",
+			"sabnzbd_queue_bytes",
+			"sabnzbd_queue_remaining_bytes",
 			"sabnzbd_warnings",
+			"sabnzbd_load1",
+			"sabnzbd_load5",
+			"sabnzbd_load15",
+			"sabnzbd_pystone_score",
+			"sabnzbd_dir_write_speed_bytes_per_second",
+			"sabnzbd_internet_bandwidth_bytes_per_second",
+			"sabnzbd_server_active_connections",
+			"sabnzbd_server_max_connections",
+			"sabnzbd_server_enabled",
+			"sabnzbd_server_error",
+			"sabnzbd_up",
+			"sabnzbd_endpoint_up",
 		)
 	})
 	require.NoError(err)
@@ -110,8 +158,674 @@ func TestCollect_FailureDoesntPanic(t *testing.T) {
 	f := strings.NewReader(expected)
 
 	require.NotPanics(func() {
-		err = testutil.CollectAndCompare(collector, f)
-		require.Error(err)
+		err = testutil.CollectAndCompare(collector, f, "sabnzbd_up", "sabnzbd_endpoint_up")
 	}, "Collecting metrics should not panic on failure")
+	require.NoError(err)
+
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_queue_length"))
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_server_downloaded_bytes"))
+}
+
+func TestCollect_PartialFailure(t *testing.T) {
+	parameters := []struct {
+		name          string
+		failing       string
+		present       []string
+		missing       []string
+		expectedUp    float64
+		expectedQueue float64
+		expectedStats float64
+	}{
+		{
+			name:          "server_stats failing",
+			failing:       "server_stats",
+			present:       []string{"sabnzbd_queue_length", "sabnzbd_info", "sabnzbd_disk_used_bytes"},
+			missing:       []string{"sabnzbd_downloaded_bytes", "sabnzbd_server_downloaded_bytes"},
+			expectedUp:    1,
+			expectedQueue: 1,
+			expectedStats: 0,
+		},
+		{
+			name:          "queue failing",
+			failing:       "queue",
+			present:       []string{"sabnzbd_downloaded_bytes", "sabnzbd_server_downloaded_bytes"},
+			missing:       []string{"sabnzbd_queue_length", "sabnzbd_info", "sabnzbd_disk_used_bytes"},
+			expectedUp:    1,
+			expectedQueue: 0,
+			expectedStats: 1,
+		},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("mode") == tt.failing {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					// Skip the fixture response
+					r.URL.RawQuery = "mode=failed"
+				}
+			})
+			require.NoError(err)
+
+			defer ts.Close()
+
+			collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+			require.NoError(err)
+
+			for _, name := range tt.present {
+				require.NotZero(testutil.CollectAndCount(collector, name), name)
+			}
+
+			for _, name := range tt.missing {
+				require.Zero(testutil.CollectAndCount(collector, name), name)
+			}
+
+			expected := fmt.Sprintf(`
+# HELP sabnzbd_endpoint_up Was the last query of the SabnzbD API endpoint successful
+# TYPE sabnzbd_endpoint_up gauge
+sabnzbd_endpoint_up{endpoint="fullstatus",target="%[1]s"} 1
+sabnzbd_endpoint_up{endpoint="history",target="%[1]s"} 1
+sabnzbd_endpoint_up{endpoint="queue",target="%[1]s"} %[3]v
+sabnzbd_endpoint_up{endpoint="server_stats",target="%[1]s"} %[4]v
+sabnzbd_endpoint_up{endpoint="warnings",target="%[1]s"} 1
+# HELP sabnzbd_up Could the SabnzbD instance be queried (1 if any endpoint responded successfully)
+# TYPE sabnzbd_up gauge
+sabnzbd_up{target="%[1]s"} %[2]v
+`, ts.URL, tt.expectedUp, tt.expectedQueue, tt.expectedStats)
+			err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sabnzbd_up", "sabnzbd_endpoint_up")
+			require.NoError(err)
+		})
+	}
+}
+
+func TestCollect_NoSpeedLimit(t *testing.T) {
+	require := require.New(t)
+
+	queue, err := os.ReadFile("../../pkg/models/test_fixtures/queue_speedlimit_none.json")
+	require.NoError(err)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "queue" {
+			_, err := w.Write(queue)
+			require.NoError(err)
+			// Skip the fixture response
+			r.URL.RawQuery = "mode=replaced"
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_speed_limit_ratio Speed Limit of the SabnzbD instance as a fraction of its configured line speed
+# TYPE sabnzbd_speed_limit_ratio gauge
+sabnzbd_speed_limit_ratio{target="%s"} 1
+`, ts.URL)
+
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"sabnzbd_speed_limit_ratio",
+		"sabnzbd_speed_limit_bytes_per_second",
+	)
+	require.NoError(err)
+}
+
+func TestCollect_History(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "history" {
+			require.Equal("100", r.URL.Query().Get("limit"))
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_history_downloaded_bytes_total Total Bytes Downloaded for jobs finished by the SabnzbD instance by category
+# TYPE sabnzbd_history_downloaded_bytes_total counter
+sabnzbd_history_downloaded_bytes_total{category="software",target="%[1]s"} 0
+# HELP sabnzbd_history_jobs_total Total jobs finished by the SabnzbD instance by status (completed, failed) and category
+# TYPE sabnzbd_history_jobs_total counter
+sabnzbd_history_jobs_total{category="software",status="completed",target="%[1]s"} 0
+sabnzbd_history_jobs_total{category="software",status="failed",target="%[1]s"} 0
+`, ts.URL)
+
+	// The jobs in the history at startup are the baseline, and aren't counted by later scrapes
+	for i := 0; i < 2; i++ {
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+			"sabnzbd_history_jobs_total", "sabnzbd_history_downloaded_bytes_total")
+		require.NoError(err)
+	}
+
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_history_download_duration_seconds"))
+}
+
+func TestCollect_Warnings(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithWarningCategories(map[string]string{
+		"server_connection": "",
+		"ignored":           "will be ignored",
+	}))
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_warnings_total Total warnings logged by the SabnzbD instance by level (warning, error) and category
+# TYPE sabnzbd_warnings_total counter
+sabnzbd_warnings_total{category="crc",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="crc",level="warning",target="%[1]s"} 0
+sabnzbd_warnings_total{category="disk_full",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="disk_full",level="warning",target="%[1]s"} 0
+sabnzbd_warnings_total{category="ignored",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="ignored",level="warning",target="%[1]s"} 0
+sabnzbd_warnings_total{category="other",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="other",level="warning",target="%[1]s"} 0
+sabnzbd_warnings_total{category="repair",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="repair",level="warning",target="%[1]s"} 0
+sabnzbd_warnings_total{category="server_login",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="server_login",level="warning",target="%[1]s"} 0
+sabnzbd_warnings_total{category="unpack",level="error",target="%[1]s"} 0
+sabnzbd_warnings_total{category="unpack",level="warning",target="%[1]s"} 0
+`, ts.URL)
+
+	// The warnings at startup are the baseline, and aren't counted by later scrapes
+	for i := 0; i < 2; i++ {
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sabnzbd_warnings_total")
+		require.NoError(err)
+	}
+
+	_, err = NewSabnzbdExporter(ts.URL, API_KEY, WithWarningCategories(map[string]string{"broken": "("}))
 	require.Error(err)
 }
+
+func TestCollect_EvictsMissingServers(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	store := LoadStateStore(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(store.Update(ts.URL, TargetState{
+		Servers: map[string]ServerState{"removed.example.tld": {Total: 100, Day: "2022-12-29"}},
+	}))
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithStateStore(store), WithServerGracePeriod(time.Nanosecond))
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_tracked_servers Number of UseNet Servers whose counters are exported, including those in their grace period after missing from SabnzbD
+# TYPE sabnzbd_tracked_servers gauge
+sabnzbd_tracked_servers{target="%[1]s"} 2
+# HELP sabnzbd_server_downloaded_bytes Total Bytes Downloaded from UseNet Server
+# TYPE sabnzbd_server_downloaded_bytes counter
+sabnzbd_server_downloaded_bytes{server="server1.example.tld",target="%[1]s"} 4.8069637e+07
+sabnzbd_server_downloaded_bytes{server="server2.example.tld",target="%[1]s"} 1.10895796e+08
+`, ts.URL)
+
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"sabnzbd_tracked_servers",
+		"sabnzbd_server_downloaded_bytes",
+	)
+	require.NoError(err)
+	require.NotContains(store.Get(ts.URL).Servers, "removed.example.tld")
+}
+
+func TestCollect_QueueJobMetrics(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithQueueJobMetrics(1))
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_queue_job_eta_seconds Estimated Time Remaining to Download the job in the SabnzbD instance's queue
+# TYPE sabnzbd_queue_job_eta_seconds gauge
+sabnzbd_queue_job_eta_seconds{category="software",name="Ubuntu.22.04.LTS",nzo_id="SABnzbd_nzo_p86tgx",priority="Normal",target="%[1]s"} 1004
+# HELP sabnzbd_queue_job_position Position of the job in the SabnzbD instance's queue (0 based)
+# TYPE sabnzbd_queue_job_position gauge
+sabnzbd_queue_job_position{category="software",name="Ubuntu.22.04.LTS",nzo_id="SABnzbd_nzo_p86tgx",priority="Normal",target="%[1]s"} 0
+# HELP sabnzbd_queue_job_progress_ratio Fraction of the job in the SabnzbD instance's queue downloaded (0-1)
+# TYPE sabnzbd_queue_job_progress_ratio gauge
+sabnzbd_queue_job_progress_ratio{category="software",name="Ubuntu.22.04.LTS",nzo_id="SABnzbd_nzo_p86tgx",priority="Normal",target="%[1]s"} 0.48
+# HELP sabnzbd_queue_job_remaining_bytes Bytes Remaining to Download of the job in the SabnzbD instance's queue
+# TYPE sabnzbd_queue_job_remaining_bytes gauge
+sabnzbd_queue_job_remaining_bytes{category="software",name="Ubuntu.22.04.LTS",nzo_id="SABnzbd_nzo_p86tgx",priority="Normal",target="%[1]s"} 1.097859072e+09
+# HELP sabnzbd_queue_jobs_dropped Jobs in the SabnzbD instance's queue currently left out of the per-job metrics by their limit
+# TYPE sabnzbd_queue_jobs_dropped gauge
+sabnzbd_queue_jobs_dropped{target="%[1]s"} 1
+`, ts.URL)
+	// Further queries of the same queue don't add to the dropped jobs
+	for i := 0; i < 2; i++ {
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+			"sabnzbd_queue_job_eta_seconds",
+			"sabnzbd_queue_job_position",
+			"sabnzbd_queue_job_progress_ratio",
+			"sabnzbd_queue_job_remaining_bytes",
+			"sabnzbd_queue_jobs_dropped",
+		)
+		require.NoError(err)
+	}
+}
+
+func TestCollect_NoQueueJobMetricsByDefault(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	require.Zero(testutil.CollectAndCount(collector,
+		"sabnzbd_queue_job_progress_ratio",
+		"sabnzbd_queue_jobs_dropped",
+	))
+}
+
+func TestSanitizeJobName(t *testing.T) {
+	parameters := []struct {
+		name     string
+		in       string
+		expected string
+	}{
+		{"plain", "Ubuntu.22.04.LTS", "Ubuntu.22.04.LTS"},
+		{"control characters", "Ubuntu\n22.04\tLTS", "Ubuntu_22.04_LTS"},
+		{"invalid utf-8", "Ubuntu\xff22.04", "Ubuntu_22.04"},
+		{"unicode", "Überraschung", "Überraschung"},
+		{"truncated", strings.Repeat("a", 100), strings.Repeat("a", 64)},
+		{"truncated unicode", strings.Repeat("ü", 100), strings.Repeat("ü", 64)},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, sanitizeJobName(tt.in))
+		})
+	}
+}
+
+func TestCollect_WithTargetName(t *testing.T) {
+	require := require.New(t)
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTargetName("home"))
+	require.NoError(err)
+
+	expected := `
+# HELP sabnzbd_queue_length Total Number of Items in the SabnzbD instance's queue
+# TYPE sabnzbd_queue_length gauge
+sabnzbd_queue_length{target="home"} 2
+`
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sabnzbd_queue_length")
+	require.NoError(err)
+}
+
+func TestCollect_PollingServesSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	var queueRequests int32
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "queue" {
+			atomic.AddInt32(&queueRequests, 1)
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithPollInterval(time.Hour))
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collector.Start(ctx)
+	require.Eventually(func() bool {
+		return atomic.LoadInt32(&queueRequests) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		testutil.CollectAndCount(collector)
+	}
+
+	require.Equal(int32(1), atomic.LoadInt32(&queueRequests))
+	require.Equal(1, testutil.CollectAndCount(collector, "sabnzbd_last_poll_timestamp_seconds"))
+	require.Equal(1, testutil.CollectAndCount(collector, "sabnzbd_snapshot_age_seconds"))
+}
+
+func TestCollect_PollingRefreshesSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	var queueRequests int32
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "queue" {
+			atomic.AddInt32(&queueRequests, 1)
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithPollInterval(10*time.Millisecond))
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	collector.Start(ctx)
+	require.Eventually(func() bool {
+		return atomic.LoadInt32(&queueRequests) >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := atomic.LoadInt32(&queueRequests)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(stopped, atomic.LoadInt32(&queueRequests), "polling should stop once the context is done")
+}
+
+func TestStart_CancelsPoll(t *testing.T) {
+	require := require.New(t)
+
+	var started, stopped sync.Once
+	requested := make(chan struct{})
+	cancelled := make(chan struct{})
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Do(func() { close(requested) })
+		select {
+		case <-r.Context().Done():
+			stopped.Do(func() { close(cancelled) })
+		case <-done:
+		}
+	}))
+
+	defer ts.Close()
+	defer close(done)
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithPollInterval(time.Hour), WithTimeout(time.Hour))
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	collector.Start(ctx)
+	<-requested
+	cancel()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		require.Fail("the poll should be cancelled once the context is done")
+	}
+}
+
+func TestCollect_NoPollingMetricsWithoutPolling(t *testing.T) {
+	require := require.New(t)
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_last_poll_timestamp_seconds"))
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_snapshot_age_seconds"))
+}
+
+func collectAll(c prometheus.Collector) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+
+	c.Collect(ch)
+	close(ch)
+	<-done
+}
+
+func TestCollect_CoalescesConcurrentScrapes(t *testing.T) {
+	require := require.New(t)
+
+	var queueRequests int32
+	release := make(chan struct{})
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "queue" {
+			atomic.AddInt32(&queueRequests, 1)
+			<-release
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collectAll(collector)
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(int32(1), atomic.LoadInt32(&queueRequests))
+	require.Equal(1.0, testutil.ToFloat64(collector.scrapes.WithLabelValues(ts.URL, "fetched")))
+	require.Equal(4.0, testutil.ToFloat64(collector.scrapes.WithLabelValues(ts.URL, "coalesced")))
+
+	// Without a result ttl, the next scrape fetches again
+	collectAll(collector)
+	require.Equal(int32(2), atomic.LoadInt32(&queueRequests))
+	require.Equal(2.0, testutil.ToFloat64(collector.scrapes.WithLabelValues(ts.URL, "fetched")))
+}
+
+func TestCollect_ResultTTL(t *testing.T) {
+	require := require.New(t)
+
+	var queueRequests int32
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "queue" {
+			atomic.AddInt32(&queueRequests, 1)
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithResultTTL(time.Hour))
+	require.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		collectAll(collector)
+	}
+
+	require.Equal(int32(1), atomic.LoadInt32(&queueRequests))
+	require.Equal(1.0, testutil.ToFloat64(collector.scrapes.WithLabelValues(ts.URL, "fetched")))
+	require.Equal(2.0, testutil.ToFloat64(collector.scrapes.WithLabelValues(ts.URL, "cached")))
+
+	collector.lock.Lock()
+	collector.snapshot.time = time.Now().Add(-2 * time.Hour)
+	collector.lock.Unlock()
+
+	collectAll(collector)
+	require.Equal(int32(2), atomic.LoadInt32(&queueRequests))
+}
+
+func TestCollect_CountsRetries(t *testing.T) {
+	require := require.New(t)
+
+	var failed int32
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "queue" && atomic.CompareAndSwapInt32(&failed, 0, 1) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			// Skip the fixture response
+			r.URL.RawQuery = "mode=failed"
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithClientOptions(
+		client.WithRetryPolicy(client.RetryPolicy{
+			MaxAttempts: 2,
+			BaseBackoff: time.Millisecond,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		}),
+	))
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_client_retries_total Total retried queries of SabnzbD API endpoints by reason
+# TYPE sabnzbd_client_retries_total counter
+sabnzbd_client_retries_total{endpoint="queue",reason="http_503",target="%[1]s"} 1
+# HELP sabnzbd_up Could the SabnzbD instance be queried (1 if any endpoint responded successfully)
+# TYPE sabnzbd_up gauge
+sabnzbd_up{target="%[1]s"} 1
+`, ts.URL)
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sabnzbd_client_retries_total", "sabnzbd_up")
+	require.NoError(err)
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_endpoint_errors_total"))
+}
+
+func TestCollect_APIErrorFailsEndpoint(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"status": false, "error": "API Key Incorrect"}`))
+		require.NoError(err)
+	}))
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	expected := fmt.Sprintf(`
+# HELP sabnzbd_endpoint_errors_total Total failed queries of SabnzbD API endpoints by class of error
+# TYPE sabnzbd_endpoint_errors_total counter
+sabnzbd_endpoint_errors_total{class="auth",endpoint="fullstatus",target="%[1]s"} 1
+sabnzbd_endpoint_errors_total{class="auth",endpoint="history",target="%[1]s"} 1
+sabnzbd_endpoint_errors_total{class="auth",endpoint="queue",target="%[1]s"} 1
+sabnzbd_endpoint_errors_total{class="auth",endpoint="server_stats",target="%[1]s"} 1
+sabnzbd_endpoint_errors_total{class="auth",endpoint="warnings",target="%[1]s"} 1
+# HELP sabnzbd_up Could the SabnzbD instance be queried (1 if any endpoint responded successfully)
+# TYPE sabnzbd_up gauge
+sabnzbd_up{target="%[1]s"} 0
+`, ts.URL)
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sabnzbd_endpoint_errors_total", "sabnzbd_up")
+	require.NoError(err)
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_queue_length"))
+	require.Equal(0, testutil.CollectAndCount(collector, "sabnzbd_downloaded_bytes"))
+}
+
+func TestStart_DiscoversAPIPath(t *testing.T) {
+	require := require.New(t)
+
+	discovered := make(chan string, 2)
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") != "version" {
+			return
+		}
+
+		discovered <- r.URL.Path
+		if r.URL.Path == "/sabnzbd/api" {
+			_, err := w.Write([]byte(`{"version": "3.7.2"}`))
+			require.NoError(err)
+		}
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithClientOptions(client.WithAPIPath("auto")))
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collector.Start(ctx)
+
+	select {
+	case path := <-discovered:
+		require.Equal("/sabnzbd/api", path)
+	case <-time.After(time.Second):
+		require.Fail("api path wasn't discovered on start")
+	}
+
+	require.Equal(1, testutil.CollectAndCount(collector, "sabnzbd_queue_length"))
+}
+
+func TestCollect_RedactsSecrets(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:1/sabnzbd/api?"+r.URL.RawQuery, http.StatusFound)
+	}))
+	defer ts.Close()
+
+	baseURL := strings.Replace(ts.URL, "http://", "http://user:hunter2@", 1)
+
+	var logs strings.Builder
+
+	// The endpoints are queried, and log, concurrently
+	logger := log.Logger
+	log.Logger = zerolog.New(zerolog.SyncWriter(&logs))
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+
+	t.Cleanup(func() {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(zerolog.FatalLevel)
+	})
+
+	collector, err := NewSabnzbdExporter(baseURL, API_KEY, WithClientOptions(
+		client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 2, StatusCodes: []int{}}),
+	))
+	require.NoError(err)
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(collector)
+
+	mfs, err := reg.Gather()
+	require.NoError(err)
+
+	metrics := fmt.Sprint(mfs)
+	require.Contains(metrics, "user:REDACTED@")
+	require.Contains(logs.String(), "Failed to query endpoint")
+	require.Contains(logs.String(), "apikey=REDACTED")
+
+	for _, secret := range []string{API_KEY, "hunter2"} {
+		require.NotContains(metrics, secret)
+		require.NotContains(logs.String(), secret)
+	}
+}
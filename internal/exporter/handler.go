@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Instance is an exporter served by the metrics handler, along with the
+// extra labels added to all of its metrics.
+type Instance struct {
+	Exporter *SabnzbdExporter
+	Labels   prometheus.Labels
+}
+
+// NewMetricsHandler serves the metrics gathered by static, and those of every
+// instance, collected within the time budget of the scrape.
+func NewMetricsHandler(static prometheus.Gatherer, instances []Instance, timeout ScrapeTimeout) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := timeout.Context(r)
+		defer cancel()
+
+		gatherers := prometheus.Gatherers{static}
+
+		for _, instance := range instances {
+			// Every instance describes the same metrics, so each gets its own
+			// registry, and they're merged at gather time.
+			reg := prometheus.NewPedanticRegistry()
+			prometheus.WrapRegistererWith(instance.Labels, reg).MustRegister(instance.Exporter.WithContext(ctx))
+
+			gatherers = append(gatherers, reg)
+		}
+
+		promhttp.HandlerFor(gatherConcurrently(gatherers), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// gatherConcurrently gathers every gatherer at once, as prometheus.Gatherers
+// would gather them one after another, letting a slow instance eat into the
+// time budget of the others.
+func gatherConcurrently(gatherers prometheus.Gatherers) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		gathered := make(prometheus.Gatherers, len(gatherers))
+
+		var wg sync.WaitGroup
+
+		for i, g := range gatherers {
+			wg.Add(1)
+
+			go func(i int, g prometheus.Gatherer) {
+				defer wg.Done()
+
+				mfs, err := g.Gather()
+				gathered[i] = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+					return mfs, err
+				})
+			}(i, g)
+		}
+
+		wg.Wait()
+
+		return gathered.Gather()
+	})
+}
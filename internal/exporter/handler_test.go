@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	home, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTargetName("home"))
+	require.NoError(err)
+	seedbox, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTargetName("seedbox"))
+	require.NoError(err)
+
+	static := prometheus.NewRegistry()
+	static.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{Name: "static_metric", Help: "A static metric"},
+		func() float64 { return 1 },
+	))
+
+	h := NewMetricsHandler(static, []Instance{
+		{Exporter: home, Labels: prometheus.Labels{"site": "home"}},
+		{Exporter: seedbox, Labels: prometheus.Labels{"site": "remote"}},
+	}, ScrapeTimeout{Default: 5 * time.Second})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	require.Contains(body, "static_metric 1")
+	require.Contains(body, `sabnzbd_queue_length{site="home",target="home"} 2`)
+	require.Contains(body, `sabnzbd_queue_length{site="remote",target="seedbox"} 2`)
+}
+
+func TestMetricsHandler_GathersInstancesConcurrently(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	instances := make([]Instance, 0, 5)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		ex, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTargetName(name))
+		require.NoError(err)
+
+		instances = append(instances, Instance{Exporter: ex})
+	}
+
+	h := NewMetricsHandler(prometheus.NewRegistry(), instances, ScrapeTimeout{Default: 5 * time.Second})
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Less(time.Since(start), time.Second)
+	require.Contains(rec.Body.String(), `sabnzbd_up{target="e"} 1`)
+}
@@ -0,0 +1,140 @@
+package exporter
+
+import (
+	"prometheus-sabnzbd-exporter/pkg/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DEFAULT_HISTORY_LIMIT is the number of latest history jobs queried per poll.
+// Jobs finishing faster than that between two polls are missed.
+var DEFAULT_HISTORY_LIMIT = 100
+
+// HISTORY_DURATION_BUCKETS range from 10s to about 6h.
+var HISTORY_DURATION_BUCKETS = prometheus.ExponentialBuckets(10, 3, 8)
+
+// historyTracker counts the jobs finishing in SabnzbD's history. SabnzbD only
+// keeps the jobs currently in its history, so jobs are counted once when they
+// first show up, and the counters are kept when the history is purged.
+//
+// The jobs of the first history are the baseline: their counters start at 0,
+// so that restarting the exporter doesn't look like a burst of finished jobs.
+type historyTracker struct {
+	lock sync.Mutex
+
+	// seen holds the ids of the finished jobs in the latest history, and is
+	// nil until the first history was seen.
+	seen map[string]struct{}
+	// floor is the completion time of the oldest finished job in the latest
+	// history. Older jobs were either counted or never seen, and resurface
+	// only when newer jobs are deleted, so they aren't counted.
+	floor time.Time
+
+	jobs               *prometheus.CounterVec
+	downloadedBytes    *prometheus.CounterVec
+	downloadTime       *prometheus.HistogramVec
+	postProcessingTime *prometheus.HistogramVec
+}
+
+func newHistoryTracker() *historyTracker {
+	return &historyTracker{
+		jobs: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "history_jobs_total",
+				Help:      "Total jobs finished by the SabnzbD instance by status (completed, failed) and category",
+			},
+			[]string{"target", "status", "category"},
+		),
+		downloadedBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "history_downloaded_bytes_total",
+				Help:      "Total Bytes Downloaded for jobs finished by the SabnzbD instance by category",
+			},
+			[]string{"target", "category"},
+		),
+		downloadTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "history_download_duration_seconds",
+				Help:      "Time spent downloading jobs finished by the SabnzbD instance",
+				Buckets:   HISTORY_DURATION_BUCKETS,
+			},
+			[]string{"target"},
+		),
+		postProcessingTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "history_postprocessing_duration_seconds",
+				Help:      "Time spent post-processing jobs finished by the SabnzbD instance",
+				Buckets:   HISTORY_DURATION_BUCKETS,
+			},
+			[]string{"target"},
+		),
+	}
+}
+
+// Update counts the finished jobs of stats which weren't in the previous
+// history.
+func (h *historyTracker) Update(target string, stats models.HistoryStats) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	baseline := h.seen == nil
+	seen := make(map[string]struct{}, len(stats.Jobs))
+
+	var oldest time.Time
+
+	for _, job := range stats.Jobs {
+		if !job.Finished() {
+			continue
+		}
+
+		seen[job.ID] = struct{}{}
+
+		if oldest.IsZero() || job.Completed.Before(oldest) {
+			oldest = job.Completed
+		}
+
+		if _, ok := h.seen[job.ID]; ok || job.Completed.Before(h.floor) {
+			continue
+		}
+
+		jobs := h.jobs.WithLabelValues(target, strings.ToLower(job.Status), job.Category)
+		downloadedBytes := h.downloadedBytes.WithLabelValues(target, job.Category)
+
+		if baseline {
+			continue
+		}
+
+		jobs.Inc()
+		downloadedBytes.Add(float64(job.DownloadedBytes))
+		h.downloadTime.WithLabelValues(target).Observe(job.DownloadTime.Seconds())
+		h.postProcessingTime.WithLabelValues(target).Observe(job.PostProcessingTime.Seconds())
+	}
+
+	h.seen = seen
+
+	// The floor never moves back, e.g. when the history is purged.
+	if oldest.After(h.floor) {
+		h.floor = oldest
+	}
+}
+
+func (h *historyTracker) Describe(ch chan<- *prometheus.Desc) {
+	h.jobs.Describe(ch)
+	h.downloadedBytes.Describe(ch)
+	h.downloadTime.Describe(ch)
+	h.postProcessingTime.Describe(ch)
+}
+
+func (h *historyTracker) Collect(ch chan<- prometheus.Metric) {
+	h.jobs.Collect(ch)
+	h.downloadedBytes.Collect(ch)
+	h.downloadTime.Collect(ch)
+	h.postProcessingTime.Collect(ch)
+}
@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"prometheus-sabnzbd-exporter/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func historyJob(id, status string, completed int64) models.HistoryJob {
+	return models.HistoryJob{
+		ID:                 id,
+		Status:             status,
+		Category:           "tv",
+		DownloadedBytes:    100,
+		DownloadTime:       time.Minute,
+		PostProcessingTime: 10 * time.Second,
+		Completed:          time.Unix(completed, 0),
+	}
+}
+
+func requireHistoryJobs(t *testing.T, h *historyTracker, completed, failed float64) {
+	t.Helper()
+
+	require.Equal(t, completed, testutil.ToFloat64(h.jobs.WithLabelValues("target", "completed", "tv")), "completed")
+	require.Equal(t, failed, testutil.ToFloat64(h.jobs.WithLabelValues("target", "failed", "tv")), "failed")
+}
+
+func TestHistoryTracker_CountsNewJobs(t *testing.T) {
+	require := require.New(t)
+	h := newHistoryTracker()
+
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("2", "Failed", 200),
+		historyJob("1", "Completed", 100),
+	}})
+	requireHistoryJobs(t, h, 0, 0)
+	require.Equal(2, testutil.CollectAndCount(h.jobs), "the first history initializes the counters")
+
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		{ID: "4", Status: "Extracting", Category: "tv"},
+		historyJob("3", "Completed", 300),
+		historyJob("2", "Failed", 200),
+		historyJob("1", "Completed", 100),
+	}})
+	requireHistoryJobs(t, h, 1, 0)
+
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("4", "Completed", 400),
+		historyJob("3", "Completed", 300),
+		historyJob("2", "Failed", 200),
+	}})
+	requireHistoryJobs(t, h, 2, 0)
+
+	require.Equal(200.0, testutil.ToFloat64(h.downloadedBytes.WithLabelValues("target", "tv")))
+}
+
+func TestHistoryTracker_Purged(t *testing.T) {
+	h := newHistoryTracker()
+
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("1", "Completed", 100),
+	}})
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("3", "Failed", 300),
+		historyJob("2", "Completed", 200),
+		historyJob("1", "Completed", 100),
+	}})
+	requireHistoryJobs(t, h, 1, 1)
+
+	h.Update("target", models.HistoryStats{})
+	requireHistoryJobs(t, h, 1, 1)
+
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("4", "Completed", 400),
+	}})
+	requireHistoryJobs(t, h, 2, 1)
+}
+
+func TestHistoryTracker_EmptyBaseline(t *testing.T) {
+	h := newHistoryTracker()
+
+	h.Update("target", models.HistoryStats{})
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("1", "Completed", 100),
+	}})
+	requireHistoryJobs(t, h, 1, 0)
+}
+
+func TestHistoryTracker_DeletedJobsDontResurfaceOlderJobs(t *testing.T) {
+	h := newHistoryTracker()
+
+	// Only the latest two jobs are queried
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("3", "Completed", 300),
+		historyJob("2", "Completed", 200),
+	}})
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("4", "Completed", 400),
+		historyJob("3", "Completed", 300),
+	}})
+	requireHistoryJobs(t, h, 1, 0)
+
+	// Deleting job 4 brings job 2 back into the queried jobs
+	h.Update("target", models.HistoryStats{Jobs: []models.HistoryJob{
+		historyJob("3", "Completed", 300),
+		historyJob("2", "Completed", 200),
+	}})
+	requireHistoryJobs(t, h, 1, 0)
+}
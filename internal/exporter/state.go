@@ -0,0 +1,186 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// STATE_VERSION is the version of the state file's format. Files of other
+// versions are ignored.
+const STATE_VERSION = 1
+
+// TargetState is the persisted state of the counters of a SabnzbD instance.
+type TargetState struct {
+	Total   TotalState
+	Servers map[string]ServerState
+}
+
+// TotalState is the persisted state of the total bytes downloaded.
+type TotalState struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+}
+
+// ServerState is the persisted state of the counters of a news server.
+type ServerState struct {
+	Total                     int    `json:"total"`
+	TotalOffset               int    `json:"total_offset"`
+	Day                       string `json:"day"`
+	ArticlesTriedHistorical   int    `json:"articles_tried_historical"`
+	ArticlesTriedToday        int    `json:"articles_tried_today"`
+	ArticlesSuccessHistorical int    `json:"articles_success_historical"`
+	ArticlesSuccessToday      int    `json:"articles_success_today"`
+}
+
+// stateFile is the format of the state file.
+type stateFile struct {
+	Version int                               `json:"version"`
+	Targets map[string]map[string]ServerState `json:"targets"`          // target -> server -> state
+	Totals  map[string]TotalState             `json:"totals,omitempty"` // target -> state
+}
+
+// StateStore persists the counters of the exporters' ServersStatsCaches to a
+// file, so that they survive restarts of the exporter. The file is rewritten
+// atomically whenever the counters change.
+type StateStore struct {
+	path string
+
+	lock    sync.Mutex
+	targets map[string]TargetState
+}
+
+// LoadStateStore loads the state file at path. A missing file is a clean
+// start, as is a corrupt file or one of another version, which is logged.
+func LoadStateStore(path string) *StateStore {
+	s := &StateStore{
+		path:    path,
+		targets: make(map[string]TargetState),
+	}
+
+	targets, err := readStateFile(path)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("path", path).
+			Msg("Ignoring state file, starting from a clean state")
+
+		return s
+	}
+
+	if targets != nil {
+		s.targets = targets
+	}
+
+	return s
+}
+
+func readStateFile(path string) (map[string]TargetState, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read state file: %w", err)
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("Failed to decode state file: %w", err)
+	}
+
+	if state.Version != STATE_VERSION {
+		return nil, fmt.Errorf("Unsupported state file version %d", state.Version)
+	}
+
+	targets := make(map[string]TargetState, len(state.Targets))
+	for target, servers := range state.Targets {
+		targets[target] = TargetState{Total: state.Totals[target], Servers: servers}
+	}
+
+	return targets, nil
+}
+
+// Get returns the state of target.
+func (s *StateStore) Get(target string) TargetState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.targets[target]
+}
+
+// Update sets the state of target, writing the file when it changed.
+func (s *StateStore) Update(target string, state TargetState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if reflect.DeepEqual(s.targets[target], state) {
+		return nil
+	}
+
+	s.targets[target] = state
+
+	return s.write()
+}
+
+// Save writes the file.
+func (s *StateStore) Save() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.write()
+}
+
+// write replaces the file with a temporary file written next to it, so that
+// the file is never left half written.
+func (s *StateStore) write() error {
+	file := stateFile{
+		Version: STATE_VERSION,
+		Targets: make(map[string]map[string]ServerState, len(s.targets)),
+		Totals:  make(map[string]TotalState, len(s.targets)),
+	}
+
+	for target, state := range s.targets {
+		file.Targets[target] = state.Servers
+		file.Totals[target] = state.Total
+	}
+
+	b, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("Failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to write state file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write state file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write state file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("Failed to write state file: %w", err)
+	}
+
+	return nil
+}
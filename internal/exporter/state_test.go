@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"prometheus-sabnzbd-exporter/pkg/models"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var TEST_TARGET_STATE = TargetState{
+	Total: TotalState{Total: 1000, Offset: 500},
+	Servers: map[string]ServerState{
+		"server1": {
+			Total:                     100,
+			TotalOffset:               50,
+			Day:                       "2020-01-02",
+			ArticlesTriedHistorical:   10,
+			ArticlesTriedToday:        2,
+			ArticlesSuccessHistorical: 9,
+			ArticlesSuccessToday:      1,
+		},
+	},
+}
+
+func TestStateStore_RoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	store := LoadStateStore(path)
+	require.Zero(store.Get("target"))
+
+	require.NoError(store.Update("target", TEST_TARGET_STATE))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(err)
+	require.Len(entries, 1, "no temporary files are left behind")
+
+	require.Equal(TEST_TARGET_STATE, LoadStateStore(path).Get("target"))
+}
+
+func TestStateStore_WritesOnlyChanges(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := LoadStateStore(path)
+	require.NoError(store.Update("target", TEST_TARGET_STATE))
+	require.NoError(os.Remove(path))
+
+	require.NoError(store.Update("target", TEST_TARGET_STATE))
+	require.NoFileExists(path)
+
+	require.NoError(store.Save())
+	require.FileExists(path)
+}
+
+func TestLoadStateStore_WithoutTotals(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(os.WriteFile(path, []byte(`{"version": 1, "targets": {"target": {"server1": {"day": "2020-01-02"}}}}`), 0o600))
+
+	require.Equal(TargetState{
+		Servers: map[string]ServerState{"server1": {Day: "2020-01-02"}},
+	}, LoadStateStore(path).Get("target"))
+}
+
+func TestLoadStateStore_Invalid(t *testing.T) {
+	parameters := []struct {
+		name    string
+		content string
+	}{
+		{"corrupt", `{"version": 1, "targets": {`},
+		{"unknown version", `{"version": 2, "targets": {"target": {"server1": {"day": "2020-01-02"}}}}`},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "state.json")
+			require.NoError(os.WriteFile(path, []byte(tt.content), 0o600))
+
+			store := LoadStateStore(path)
+			require.Zero(store.Get("target"))
+
+			// The bad file is replaced by the next update
+			require.NoError(store.Update("target", TEST_TARGET_STATE))
+			require.Equal(TEST_TARGET_STATE, LoadStateStore(path).Get("target"))
+		})
+	}
+}
+
+func TestServersStatsCache_Restore(t *testing.T) {
+	require := require.New(t)
+
+	cache := NewServersStatsCache()
+	cache.Restore(TEST_TARGET_STATE)
+	require.Equal(TEST_TARGET_STATE, cache.State())
+
+	cache.Update(models.ServerStats{
+		Servers: map[string]models.ServerStat{
+			"server1": {Total: 120, ArticlesTried: 5, ArticlesSuccess: 4, DayParsed: "2020-01-02"},
+		},
+	})
+
+	server1 := cache.GetServerMap()["server1"]
+	require.Equal(170, server1.GetTotal())
+	require.Equal(15, server1.GetArticlesTried())
+	require.Equal(13, server1.GetArticlesSuccess())
+}
+
+func TestCollect_PersistsState(t *testing.T) {
+	require := require.New(t)
+
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	require.NoError(err)
+
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTargetName("home"), WithStateStore(LoadStateStore(path)))
+	require.NoError(err)
+	require.NotZero(testutil.CollectAndCount(collector, "sabnzbd_server_articles_total"))
+
+	state := LoadStateStore(path).Get("home")
+	require.Equal(ServerState{
+		Total:                     48069637,
+		Day:                       "2022-12-29",
+		ArticlesTriedHistorical:   10416,
+		ArticlesTriedToday:        12622,
+		ArticlesSuccessHistorical: 10416,
+		ArticlesSuccessToday:      12618,
+	}, state.Servers["server1.example.tld"])
+
+	restored, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTargetName("home"), WithStateStore(LoadStateStore(path)))
+	require.NoError(err)
+	require.Equal(state, restored.cache.State())
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ScrapeTimeout derives the deadline of a scrape from the timeout prometheus
+// sends in the X-Prometheus-Scrape-Timeout-Seconds header.
+type ScrapeTimeout struct {
+	Default time.Duration // used when the header is missing or invalid
+	Offset  time.Duration // safety margin subtracted from prometheus' timeout
+}
+
+// Context returns a context which is done once the scrape's time budget runs out.
+func (t ScrapeTimeout) Context(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout := t.Default
+
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds > 0 {
+			timeout = time.Duration(seconds * float64(time.Second))
+			if timeout > t.Offset {
+				timeout -= t.Offset
+			}
+		}
+	}
+
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// contextCollector collects an exporter with the context of a single scrape.
+type contextCollector struct {
+	ctx      context.Context
+	exporter *SabnzbdExporter
+}
+
+// WithContext returns a collector which cancels its queries of SabnzbD once
+// ctx is done, for registering on a per-scrape registry.
+func (e *SabnzbdExporter) WithContext(ctx context.Context) prometheus.Collector {
+	return &contextCollector{
+		ctx:      ctx,
+		exporter: e,
+	}
+}
+
+func (c *contextCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.exporter.Describe(ch)
+}
+
+func (c *contextCollector) Collect(ch chan<- prometheus.Metric) {
+	c.exporter.CollectWithContext(c.ctx, ch)
+}
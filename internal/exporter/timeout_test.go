@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrapeTimeout_Context(t *testing.T) {
+	parameters := []struct {
+		name     string
+		header   string
+		expected time.Duration
+	}{
+		{"no header", "", 10 * time.Second},
+		{"header", "5", 4500 * time.Millisecond},
+		{"fractional header", "2.5", 2 * time.Second},
+		{"header below offset", "0.2", 200 * time.Millisecond},
+		{"invalid header", "abc", 10 * time.Second},
+		{"negative header", "-1", 10 * time.Second},
+	}
+
+	timeout := ScrapeTimeout{
+		Default: 10 * time.Second,
+		Offset:  500 * time.Millisecond,
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", tt.header)
+			}
+
+			start := time.Now()
+			ctx, cancel := timeout.Context(req)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			require.True(ok)
+			require.WithinDuration(start.Add(tt.expected), deadline, 50*time.Millisecond)
+		})
+	}
+}
+
+func TestCollectWithContext_CancelsQueries(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY)
+	require.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	count := testutil.CollectAndCount(collector.WithContext(ctx), "sabnzbd_up")
+	require.Less(time.Since(start), 2*time.Second)
+	require.Equal(1, count)
+
+	require.Equal(1.0, testutil.ToFloat64(collector.errors.WithLabelValues(ts.URL, "queue", "timeout")))
+	require.Equal(1.0, testutil.ToFloat64(collector.errors.WithLabelValues(ts.URL, "server_stats", "timeout")))
+}
+
+func TestCollect_UsesDefaultTimeout(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	collector, err := NewSabnzbdExporter(ts.URL, API_KEY, WithTimeout(100*time.Millisecond))
+	require.NoError(err)
+
+	start := time.Now()
+	testutil.CollectAndCount(collector)
+	require.Less(time.Since(start), 2*time.Second)
+	require.Equal(1.0, testutil.ToFloat64(collector.errors.WithLabelValues(ts.URL, "queue", "timeout")))
+}
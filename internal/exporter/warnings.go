@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"fmt"
+	"prometheus-sabnzbd-exporter/pkg/models"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DEFAULT_WARNING_CATEGORIES classify the warnings logged by SabnzbD by the
+// regex matching their text.
+var DEFAULT_WARNING_CATEGORIES = map[string]string{
+	"disk_full":         `(?i)(disk full|no space left|too little diskspace|not enough (free )?(disk ?)?space)`,
+	"server_login":      `(?i)(failed login|login failed|authentication failed|requires user/password|incorrect username)`,
+	"server_connection": `(?i)(failed to connect|cannot connect|connection refused|too many connections|will be ignored for)`,
+	"crc":               `(?i)\bcrc\b`,
+	"unpack":            `(?i)(unpack|unrar|un7zip|unzip)`,
+	"repair":            `(?i)(repair failed|par2|verification failed)`,
+}
+
+// WARNING_LEVELS are the levels of warnings logged by SabnzbD.
+var WARNING_LEVELS = []string{"warning", "error"}
+
+// OTHER_WARNING_CATEGORY is the category of warnings matching no category.
+const OTHER_WARNING_CATEGORY = "other"
+
+// WarningCategory classifies the warnings whose text matches Regexp.
+type WarningCategory struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// WarningCategories are matched in order, the first matching category wins.
+type WarningCategories []WarningCategory
+
+// Classify returns the name of the first category matching text, or
+// OTHER_WARNING_CATEGORY.
+func (c WarningCategories) Classify(text string) string {
+	for _, category := range c {
+		if category.Regexp.MatchString(text) {
+			return category.Name
+		}
+	}
+
+	return OTHER_WARNING_CATEGORY
+}
+
+// NewWarningCategories compiles DEFAULT_WARNING_CATEGORIES, with categories
+// added or replaced by overrides. Categories overridden with an empty regex
+// are removed. Categories are matched in the order of their name.
+func NewWarningCategories(overrides map[string]string) (WarningCategories, error) {
+	merged := make(map[string]string, len(DEFAULT_WARNING_CATEGORIES)+len(overrides))
+	for name, expr := range DEFAULT_WARNING_CATEGORIES {
+		merged[name] = expr
+	}
+
+	for name, expr := range overrides {
+		if expr == "" {
+			delete(merged, name)
+			continue
+		}
+
+		merged[name] = expr
+	}
+
+	categories := make(WarningCategories, 0, len(merged))
+
+	for name, expr := range merged {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex of warning category %s: %w", name, err)
+		}
+
+		categories = append(categories, WarningCategory{Name: name, Regexp: re})
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
+	return categories, nil
+}
+
+// warningKey identifies a warning across polls, as SabnzbD doesn't assign ids.
+type warningKey struct {
+	time int64
+	text string
+}
+
+// warningsTracker counts the warnings logged by SabnzbD. SabnzbD only keeps
+// its latest warnings, so warnings are counted once when they first show up.
+//
+// The warnings of the first poll are the baseline: they aren't counted, so
+// that restarting the exporter doesn't look like a burst of warnings.
+type warningsTracker struct {
+	lock       sync.Mutex
+	categories WarningCategories
+
+	// seen holds the warnings of the latest poll, and is nil until the first
+	// poll.
+	seen map[warningKey]struct{}
+
+	warnings *prometheus.CounterVec
+}
+
+func newWarningsTracker(categories WarningCategories) *warningsTracker {
+	return &warningsTracker{
+		categories: categories,
+		warnings: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: METRIC_PREFIX,
+				Name:      "warnings_total",
+				Help:      "Total warnings logged by the SabnzbD instance by level (warning, error) and category",
+			},
+			[]string{"target", "level", "category"},
+		),
+	}
+}
+
+// Update counts the warnings which weren't in the previous poll.
+func (w *warningsTracker) Update(target string, warnings []models.Warning) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	baseline := w.seen == nil
+	if baseline {
+		// Initialize the counters of every known category, so that the first
+		// warning of a category shows up as an increase.
+		for _, level := range WARNING_LEVELS {
+			for _, c := range w.categories {
+				w.warnings.WithLabelValues(target, level, c.Name)
+			}
+
+			w.warnings.WithLabelValues(target, level, OTHER_WARNING_CATEGORY)
+		}
+	}
+
+	seen := make(map[warningKey]struct{}, len(warnings))
+
+	for _, warning := range warnings {
+		key := warningKey{time: warning.Time.Unix(), text: warning.Text}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		if _, ok := w.seen[key]; ok {
+			continue
+		}
+
+		counter := w.warnings.WithLabelValues(target, warning.Level, w.categories.Classify(warning.Text))
+		if !baseline {
+			counter.Inc()
+		}
+	}
+
+	w.seen = seen
+}
+
+func (w *warningsTracker) Describe(ch chan<- *prometheus.Desc) {
+	w.warnings.Describe(ch)
+}
+
+func (w *warningsTracker) Collect(ch chan<- prometheus.Metric) {
+	w.warnings.Collect(ch)
+}
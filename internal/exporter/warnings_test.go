@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"prometheus-sabnzbd-exporter/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func warning(level, text string, t int64) models.Warning {
+	return models.Warning{Level: level, Text: text, Time: time.Unix(t, 0)}
+}
+
+func newTestWarningsTracker(t *testing.T) *warningsTracker {
+	categories, err := NewWarningCategories(nil)
+	require.NoError(t, err)
+
+	return newWarningsTracker(categories)
+}
+
+func TestNewWarningCategories(t *testing.T) {
+	require := require.New(t)
+
+	categories, err := NewWarningCategories(map[string]string{
+		"quota":  "(?i)quota",
+		"repair": "",
+		"crc":    "(?i)checksum",
+	})
+	require.NoError(err)
+
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+
+	require.Equal([]string{"crc", "disk_full", "quota", "server_connection", "server_login", "unpack"}, names)
+	require.True(categories[0].Regexp.MatchString("Checksum mismatch"))
+
+	_, err = NewWarningCategories(map[string]string{"broken": "("})
+	require.Error(err)
+}
+
+func TestWarningCategories_Classify(t *testing.T) {
+	categories, err := NewWarningCategories(nil)
+	require.NoError(t, err)
+
+	parameters := []struct {
+		text     string
+		expected string
+	}{
+		{"Too little diskspace forcing PAUSE", "disk_full"},
+		{"Failed login for server news.example.com", "server_login"},
+		{"Server news.example.com requires user/password", "server_login"},
+		{"Too many connections to server news.example.com", "server_connection"},
+		{"Server news.example.com will be ignored for 10 minutes", "server_connection"},
+		{"CRC Error in ubuntu.part01.rar", "crc"},
+		{"Unpacking failed, archive requires a password", "unpack"},
+		{"Repair failed, not enough repair blocks (12 short)", "repair"},
+		{"Something else went wrong", "other"},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.text, func(t *testing.T) {
+			require.Equal(t, tt.expected, categories.Classify(tt.text))
+		})
+	}
+}
+
+func TestWarningsTracker_CountsNewWarnings(t *testing.T) {
+	require := require.New(t)
+	w := newTestWarningsTracker(t)
+
+	w.Update("target", []models.Warning{
+		warning("warning", "Too little diskspace forcing PAUSE", 100),
+	})
+	require.Equal(0.0, testutil.ToFloat64(w.warnings.WithLabelValues("target", "warning", "disk_full")))
+	require.Equal(14, testutil.CollectAndCount(w.warnings), "the first poll initializes every category")
+
+	w.Update("target", []models.Warning{
+		warning("warning", "Too little diskspace forcing PAUSE", 100),
+		warning("warning", "Too little diskspace forcing PAUSE", 200),
+		warning("error", "Failed login for server news.example.com", 200),
+		warning("error", "Failed login for server news.example.com", 200),
+	})
+	require.Equal(1.0, testutil.ToFloat64(w.warnings.WithLabelValues("target", "warning", "disk_full")))
+	require.Equal(1.0, testutil.ToFloat64(w.warnings.WithLabelValues("target", "error", "server_login")))
+
+	// Warnings are cleared when SabnzbD restarts
+	w.Update("target", []models.Warning{})
+	w.Update("target", []models.Warning{
+		warning("warning", "Too little diskspace forcing PAUSE", 300),
+	})
+	require.Equal(2.0, testutil.ToFloat64(w.warnings.WithLabelValues("target", "warning", "disk_full")))
+	require.Equal(1.0, testutil.ToFloat64(w.warnings.WithLabelValues("target", "error", "server_login")))
+}
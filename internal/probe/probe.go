@@ -0,0 +1,203 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"prometheus-sabnzbd-exporter/internal/config"
+	"prometheus-sabnzbd-exporter/internal/exporter"
+	"prometheus-sabnzbd-exporter/internal/redact"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+var DEFAULT_MODULE = "default"
+
+// DEFAULT_MAX_TARGETS caps the exporters kept between probes. When exceeded,
+// the least recently probed exporter is dropped.
+var DEFAULT_MAX_TARGETS = 1000
+
+// DEFAULT_IDLE_TIMEOUT is how long exporters are kept without being probed.
+var DEFAULT_IDLE_TIMEOUT = time.Hour
+
+// ErrTargetNotAllowed is returned when a target isn't matched by the targets
+// of the requested module.
+var ErrTargetNotAllowed = errors.New("Target not allowed by module")
+
+// Handler serves /probe?target=<base_url>&module=<name> in the style of the
+// blackbox_exporter. Each request is gathered through its own registry, so
+// the response only ever contains metrics for the requested target.
+//
+// Exporters are kept per module & target between probes, as the
+// ServersStatsCache needs to see every scrape to keep its counters monotonic.
+// As targets are chosen by the caller, exporters idle for longer than
+// idleTimeout are dropped, and at most maxTargets are kept.
+type Handler struct {
+	modules map[string]config.Module
+	targets map[string][]*regexp.Regexp // compiled targets of each module
+	timeout exporter.ScrapeTimeout
+	opts    []exporter.Option
+
+	maxTargets  int
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	lock      sync.Mutex
+	exporters map[string]*probedExporter
+}
+
+type probedExporter struct {
+	exporter *exporter.SabnzbdExporter
+	lastUsed time.Time
+}
+
+// NewHandler builds a probe handler, applying opts to every probed exporter.
+func NewHandler(modules map[string]config.Module, timeout exporter.ScrapeTimeout, opts ...exporter.Option) *Handler {
+	targets := make(map[string][]*regexp.Regexp, len(modules))
+
+	for name, module := range modules {
+		for _, expr := range module.Targets {
+			// The targets were validated by the config
+			targets[name] = append(targets[name], regexp.MustCompile("^(?:"+expr+")$"))
+		}
+	}
+
+	return &Handler{
+		modules:     modules,
+		targets:     targets,
+		timeout:     timeout,
+		opts:        opts,
+		maxTargets:  DEFAULT_MAX_TARGETS,
+		idleTimeout: DEFAULT_IDLE_TIMEOUT,
+		now:         time.Now,
+		exporters:   make(map[string]*probedExporter),
+	}
+}
+
+func (h *Handler) getExporter(moduleName, target string) (*exporter.SabnzbdExporter, error) {
+	module, ok := h.modules[moduleName]
+	if !ok {
+		return nil, fmt.Errorf("Unknown module %q", moduleName)
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	h.evict(now)
+
+	key := moduleName + "|" + target
+	if probed, ok := h.exporters[key]; ok {
+		probed.lastUsed = now
+		return probed.exporter, nil
+	}
+
+	if !allowed(h.targets[moduleName], target) {
+		return nil, fmt.Errorf("%w %q", ErrTargetNotAllowed, moduleName)
+	}
+
+	clientOpts, err := module.Client.Options()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to configure client of module %q: %w", moduleName, err)
+	}
+
+	opts := append([]exporter.Option{exporter.WithClientOptions(clientOpts...)}, h.opts...)
+
+	ex, err := exporter.NewSabnzbdExporter(target, module.ApiKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.exporters) >= h.maxTargets {
+		h.evictOldest()
+	}
+
+	h.exporters[key] = &probedExporter{exporter: ex, lastUsed: now}
+
+	return ex, nil
+}
+
+// evict drops the exporters idle for longer than idleTimeout.
+func (h *Handler) evict(now time.Time) {
+	for key, probed := range h.exporters {
+		if now.Sub(probed.lastUsed) > h.idleTimeout {
+			delete(h.exporters, key)
+		}
+	}
+}
+
+// evictOldest drops the least recently probed exporter.
+func (h *Handler) evictOldest() {
+	var oldest string
+
+	for key, probed := range h.exporters {
+		if oldest == "" || probed.lastUsed.Before(h.exporters[oldest].lastUsed) {
+			oldest = key
+		}
+	}
+
+	delete(h.exporters, oldest)
+}
+
+// allowed returns whether a module's credentials may be sent to target, by
+// the module's compiled targets. Modules without targets allow any target.
+func allowed(targets []*regexp.Regexp, target string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+
+	for _, re := range targets {
+		if re.MatchString(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	moduleName := params.Get("module")
+	if moduleName == "" {
+		moduleName = DEFAULT_MODULE
+	}
+
+	ex, err := h.getExporter(moduleName, target)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("target", redact.URL(target)).
+			Str("module", moduleName).
+			Msg("Failed to build probe")
+
+		code := http.StatusBadRequest
+		if errors.Is(err, ErrTargetNotAllowed) {
+			code = http.StatusForbidden
+		}
+
+		http.Error(w, err.Error(), code)
+
+		return
+	}
+
+	ctx, cancel := h.timeout.Context(r)
+	defer cancel()
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(ex.WithContext(ctx))
+
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,288 @@
+package probe
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-sabnzbd-exporter/internal/config"
+	"prometheus-sabnzbd-exporter/internal/exporter"
+	"prometheus-sabnzbd-exporter/internal/redact"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var TEST_TIMEOUT = exporter.ScrapeTimeout{Default: 5 * time.Second}
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.FatalLevel)
+}
+
+func newTestServer(t *testing.T, apiKey string) *httptest.Server {
+	queue, err := os.ReadFile("../exporter/test_fixtures/queue.json")
+	require.NoError(t, err)
+	serverStats, err := os.ReadFile("../exporter/test_fixtures/server_stats.json")
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, apiKey, r.URL.Query().Get("apikey"))
+
+		switch r.URL.Query().Get("mode") {
+		case "queue":
+			_, err = w.Write(queue)
+		case "server_stats":
+			_, err = w.Write(serverStats)
+		}
+		require.NoError(t, err)
+	}))
+}
+
+func probe(h http.Handler, params url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/probe?"+params.Encode(), nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestProbe(t *testing.T) {
+	require := require.New(t)
+
+	home := newTestServer(t, "abc123")
+	defer home.Close()
+
+	seedbox := newTestServer(t, "def456")
+	defer seedbox.Close()
+
+	h := NewHandler(map[string]config.Module{
+		"default": {ApiKey: "abc123"},
+		"seedbox": {ApiKey: "def456"},
+	}, TEST_TIMEOUT)
+
+	rec := probe(h, url.Values{"target": {home.URL}})
+	require.Equal(http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(err)
+	require.Contains(string(body), `sabnzbd_queue_length{target="`+home.URL+`"} 2`)
+	require.NotContains(string(body), seedbox.URL)
+
+	rec = probe(h, url.Values{"target": {seedbox.URL}, "module": {"seedbox"}})
+	require.Equal(http.StatusOK, rec.Code)
+
+	body, err = io.ReadAll(rec.Body)
+	require.NoError(err)
+	require.Contains(string(body), `sabnzbd_queue_length{target="`+seedbox.URL+`"} 2`)
+	require.NotContains(string(body), home.URL)
+}
+
+func TestProbe_ReusesExporterPerTarget(t *testing.T) {
+	require := require.New(t)
+
+	h := NewHandler(map[string]config.Module{"default": {ApiKey: "abc123"}}, TEST_TIMEOUT)
+
+	first, err := h.getExporter("default", "http://localhost:8080")
+	require.NoError(err)
+	second, err := h.getExporter("default", "http://localhost:8080")
+	require.NoError(err)
+	other, err := h.getExporter("default", "http://localhost:8081")
+	require.NoError(err)
+
+	require.Same(first, second)
+	require.NotSame(first, other)
+}
+
+func TestProbe_EvictsExporters(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	h := NewHandler(map[string]config.Module{"default": {ApiKey: "abc123"}}, TEST_TIMEOUT)
+	h.maxTargets = 2
+	h.idleTimeout = time.Hour
+	h.now = func() time.Time { return now }
+
+	first, err := h.getExporter("default", "http://localhost:8080")
+	require.NoError(err)
+
+	now = now.Add(time.Minute)
+	_, err = h.getExporter("default", "http://localhost:8081")
+	require.NoError(err)
+
+	// The least recently probed exporter is dropped beyond maxTargets
+	now = now.Add(time.Minute)
+	_, err = h.getExporter("default", "http://localhost:8082")
+	require.NoError(err)
+	require.Len(h.exporters, 2)
+	require.NotContains(h.exporters, "default|http://localhost:8080")
+
+	// Idle exporters are dropped
+	now = now.Add(time.Hour + time.Second)
+	again, err := h.getExporter("default", "http://localhost:8080")
+	require.NoError(err)
+	require.NotSame(first, again)
+	require.Len(h.exporters, 1)
+}
+
+func TestProbe_ModuleTargets(t *testing.T) {
+	require := require.New(t)
+
+	home := newTestServer(t, "abc123")
+	defer home.Close()
+
+	h := NewHandler(map[string]config.Module{
+		"default": {ApiKey: "abc123", Targets: []string{`http://127\.0\.0\.1:\d+`}},
+	}, TEST_TIMEOUT)
+
+	rec := probe(h, url.Values{"target": {home.URL}})
+	require.Equal(http.StatusOK, rec.Code)
+
+	// Targets must match in full
+	for _, target := range []string{"http://attacker.example.com", home.URL + ".attacker.example.com"} {
+		rec = probe(h, url.Values{"target": {target}})
+		require.Equal(http.StatusForbidden, rec.Code, target)
+	}
+
+	require.Len(h.exporters, 1)
+}
+
+func TestProbe_ResetMode(t *testing.T) {
+	require := require.New(t)
+
+	// SabnzbD's statistics are cleared after the first probe
+	totals := []int{1000, 10}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "server_stats" {
+			fmt.Fprintf(w, `{"total": %d, "servers": {}}`, totals[0])
+			totals = totals[1:]
+		}
+	}))
+	defer ts.Close()
+
+	h := NewHandler(map[string]config.Module{"default": {ApiKey: "abc123"}}, TEST_TIMEOUT,
+		exporter.WithResetMode(exporter.RESET_MODE_MONOTONIC),
+	)
+
+	for _, expected := range []string{"1000", "1010"} {
+		rec := probe(h, url.Values{"target": {ts.URL}})
+		require.Equal(http.StatusOK, rec.Code)
+
+		body, err := io.ReadAll(rec.Body)
+		require.NoError(err)
+		require.Contains(string(body), `sabnzbd_downloaded_bytes{target="`+ts.URL+`"} `+expected+"\n")
+	}
+}
+
+func TestProbe_DoesntRegisterTargetSecrets(t *testing.T) {
+	require := require.New(t)
+
+	home := newTestServer(t, "abc123")
+	defer home.Close()
+
+	h := NewHandler(map[string]config.Module{"default": {ApiKey: "abc123"}}, TEST_TIMEOUT)
+
+	target := strings.Replace(home.URL, "http://", "http://u:e@", 1)
+	rec := probe(h, url.Values{"target": {target}})
+	require.Equal(http.StatusOK, rec.Code)
+
+	require.Equal("sabnzbd-home target=seedbox", redact.String("sabnzbd-home target=seedbox"))
+}
+
+func TestProbe_BadRequests(t *testing.T) {
+	parameters := []struct {
+		name   string
+		params url.Values
+	}{
+		{
+			name:   "missing target",
+			params: url.Values{},
+		},
+		{
+			name:   "unknown module",
+			params: url.Values{"target": {"http://localhost:8080"}, "module": {"nope"}},
+		},
+		{
+			name:   "invalid target",
+			params: url.Values{"target": {"::not a url::"}},
+		},
+	}
+
+	h := NewHandler(map[string]config.Module{"default": {ApiKey: "abc123"}}, TEST_TIMEOUT)
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := probe(h, tt.params)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestProbe_HonorsScrapeTimeout(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	h := NewHandler(map[string]config.Module{"default": {ApiKey: "abc123"}}, TEST_TIMEOUT)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?"+url.Values{"target": {ts.URL}}.Encode(), nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.2")
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	h.ServeHTTP(rec, req)
+
+	require.Less(time.Since(start), 2*time.Second)
+	require.Equal(http.StatusOK, rec.Code)
+	require.Contains(rec.Body.String(), `sabnzbd_up{target="`+ts.URL+`"} 0`)
+	require.Contains(rec.Body.String(), `sabnzbd_endpoint_errors_total{class="timeout",endpoint="queue",target="`+ts.URL+`"} 1`)
+}
+
+func TestProbe_ModuleTLS(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("{}"))
+		require.NoError(err)
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	require.NoError(os.WriteFile(caFile, ca, 0o600))
+
+	h := NewHandler(map[string]config.Module{
+		"default": {ApiKey: "abc123"},
+		"tls": {
+			ApiKey: "abc123",
+			Client: config.ClientConfig{TLS: config.TLSConfig{CAFile: caFile}},
+		},
+		"broken": {
+			ApiKey: "abc123",
+			Client: config.ClientConfig{TLS: config.TLSConfig{CAFile: "missing.crt"}},
+		},
+	}, TEST_TIMEOUT)
+
+	rec := probe(h, url.Values{"target": {ts.URL}, "module": {"tls"}})
+	require.Equal(http.StatusOK, rec.Code)
+	require.Contains(rec.Body.String(), `sabnzbd_up{target="`+ts.URL+`"} 1`)
+
+	rec = probe(h, url.Values{"target": {ts.URL}})
+	require.Equal(http.StatusOK, rec.Code)
+	require.Contains(rec.Body.String(), `sabnzbd_up{target="`+ts.URL+`"} 0`)
+
+	rec = probe(h, url.Values{"target": {ts.URL}, "module": {"broken"}})
+	require.Equal(http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,164 @@
+// Package redact scrubs credentials from anything that leaves the exporter:
+// errors, log fields and metric labels.
+package redact
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"prometheus-sabnzbd-exporter/pkg/secret"
+)
+
+var REDACTED = "REDACTED"
+
+// SENSITIVE_PARAMS are query parameters whose values are always redacted.
+var SENSITIVE_PARAMS = []string{"apikey", "api_key", "ma_password", "password", "token"}
+
+var (
+	paramRegexp    = regexp.MustCompile(`(?i)([?&](?:` + strings.Join(SENSITIVE_PARAMS, "|") + `)=)[^&\s"']*`)
+	userinfoRegexp = regexp.MustCompile(`(://[^/@\s"']*?):[^/@\s"']*@`)
+)
+
+var (
+	lock    sync.RWMutex
+	secrets = make(map[string]struct{})
+)
+
+// Register adds secrets which are redacted wherever they appear. It's called
+// with every api key & credential the exporter is configured with.
+func Register(secret ...string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, s := range secret {
+		if s != "" {
+			secrets[s] = struct{}{}
+		}
+	}
+}
+
+// registeredSecret registers the values of a secret.Secret as they're read.
+type registeredSecret struct {
+	secret.Secret
+
+	lock sync.Mutex
+	last string
+}
+
+func (s *registeredSecret) Value() string {
+	value := s.Secret.Value()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if value != s.last {
+		Register(value)
+		s.last = value
+	}
+
+	return value
+}
+
+// Secret returns s, registering its current value and every value read from
+// it later, so that rotated secrets are redacted too.
+func Secret(s secret.Secret) secret.Secret {
+	if s == nil {
+		return nil
+	}
+
+	r := &registeredSecret{Secret: s}
+	r.Value()
+
+	return r
+}
+
+// String redacts sensitive query parameters, passwords in urls and all
+// registered secrets from s.
+func String(s string) string {
+	s = paramRegexp.ReplaceAllString(s, "${1}"+REDACTED)
+	s = userinfoRegexp.ReplaceAllString(s, "${1}:"+REDACTED+"@")
+
+	lock.RLock()
+	registered := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		registered = append(registered, secret)
+	}
+	lock.RUnlock()
+
+	// Replace longer secrets first, so that a secret containing another one
+	// isn't partially left behind.
+	sort.Slice(registered, func(i, j int) bool {
+		return len(registered[i]) > len(registered[j])
+	})
+
+	for _, secret := range registered {
+		s = strings.ReplaceAll(s, secret, REDACTED)
+	}
+
+	return s
+}
+
+// URL redacts the password of rawURL's userinfo and sensitive query
+// parameters, leaving the rest intact for use in labels & logs. Values which
+// don't parse as url are redacted via String.
+func URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return String(rawURL)
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), REDACTED)
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for _, param := range SENSITIVE_PARAMS {
+			if q.Has(param) {
+				q.Set(param, REDACTED)
+			}
+		}
+
+		u.RawQuery = q.Encode()
+	}
+
+	return String(u.String())
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Error returns err with a redacted message, keeping it matchable with
+// errors.Is & errors.As. The url of a *url.Error is redacted in place.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = URL(urlErr.URL)
+	}
+
+	msg := String(err.Error())
+
+	if redacted, ok := err.(*redactedError); ok && redacted.msg == msg {
+		return err
+	}
+
+	return &redactedError{err: err, msg: msg}
+}
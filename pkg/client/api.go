@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"prometheus-sabnzbd-exporter/pkg/models"
+)
+
+// QueueOptions filters & pages the queue. Zero values are left to SabnzbD's
+// defaults.
+type QueueOptions struct {
+	Start    int      // Index of the first job to return
+	Limit    int      // Number of jobs to return
+	Category string   // Only return jobs of this category
+	Search   string   // Only return jobs whose name contains this
+	NzoIDs   []string // Only return these jobs
+}
+
+func (o QueueOptions) params() url.Values {
+	params := url.Values{}
+	setInt(params, "start", o.Start)
+	setInt(params, "limit", o.Limit)
+	setString(params, "cat", o.Category)
+	setString(params, "search", o.Search)
+	setString(params, "nzo_ids", strings.Join(o.NzoIDs, ","))
+
+	return params
+}
+
+// HistoryOptions filters & pages the history. Zero values are left to
+// SabnzbD's defaults.
+type HistoryOptions struct {
+	Start      int      // Index of the first job to return
+	Limit      int      // Number of jobs to return
+	Category   string   // Only return jobs of this category
+	Search     string   // Only return jobs whose name contains this
+	NzoIDs     []string // Only return these jobs
+	FailedOnly bool     // Only return failed jobs
+}
+
+func (o HistoryOptions) params() url.Values {
+	params := url.Values{}
+	setInt(params, "start", o.Start)
+	setInt(params, "limit", o.Limit)
+	setString(params, "cat", o.Category)
+	setString(params, "search", o.Search)
+	setString(params, "nzo_ids", strings.Join(o.NzoIDs, ","))
+
+	if o.FailedOnly {
+		params.Set("failed_only", "1")
+	}
+
+	return params
+}
+
+// FullStatusOptions selects the optional, more expensive parts of fullstatus.
+type FullStatusOptions struct {
+	SkipDashboard        bool // Skip the ip address & dns checks
+	CalculatePerformance bool // Measure pystone, directory speeds & bandwidth
+}
+
+func (o FullStatusOptions) params() url.Values {
+	params := url.Values{}
+	if o.SkipDashboard {
+		params.Set("skip_dashboard", "1")
+	}
+
+	if o.CalculatePerformance {
+		params.Set("calculate_performance", "1")
+	}
+
+	return params
+}
+
+// Queue returns the queue, including the jobs selected by opts.
+func (c *SabnzbdClient) Queue(ctx context.Context, opts QueueOptions) (*models.QueueResponse, error) {
+	var resp models.QueueResponse
+	if err := c.getJSON(ctx, "queue", opts.params(), &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// History returns the history, including the jobs selected by opts.
+func (c *SabnzbdClient) History(ctx context.Context, opts HistoryOptions) (*models.HistoryResponse, error) {
+	var resp models.HistoryResponse
+	if err := c.getJSON(ctx, "history", opts.params(), &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ServerStats returns the download statistics of all news servers.
+func (c *SabnzbdClient) ServerStats(ctx context.Context) (*models.ServerStatsResponse, error) {
+	var resp models.ServerStatsResponse
+	if err := c.getJSON(ctx, "server_stats", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Warnings returns the warnings & errors SabnzbD logged since its start, or
+// since they were last cleared.
+func (c *SabnzbdClient) Warnings(ctx context.Context) (*models.WarningsResponse, error) {
+	var resp models.WarningsResponse
+	if err := c.getJSON(ctx, "warnings", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Status returns the status of SabnzbD and its news servers, without the
+// performance measurements of FullStatus.
+func (c *SabnzbdClient) Status(ctx context.Context) (*models.StatusResponse, error) {
+	var resp models.StatusResponse
+	if err := c.getJSON(ctx, "status", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// FullStatus returns the status of SabnzbD and its news servers, optionally
+// measuring its performance. Measuring takes several seconds.
+func (c *SabnzbdClient) FullStatus(ctx context.Context, opts FullStatusOptions) (*models.StatusResponse, error) {
+	var resp models.StatusResponse
+	if err := c.getJSON(ctx, "fullstatus", opts.params(), &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Version returns the version of SabnzbD.
+func (c *SabnzbdClient) Version(ctx context.Context) (string, error) {
+	var resp models.VersionResponse
+	if err := c.getJSON(ctx, "version", nil, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Version, nil
+}
+
+// GetConfig returns SabnzbD's configuration, or only the given section if set.
+// Secrets like passwords & api keys are masked by SabnzbD.
+func (c *SabnzbdClient) GetConfig(ctx context.Context, section string) (*models.ConfigResponse, error) {
+	params := url.Values{}
+	setString(params, "section", section)
+
+	var resp models.ConfigResponse
+	if err := c.getJSON(ctx, "get_config", params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetCats returns the names of the configured categories.
+func (c *SabnzbdClient) GetCats(ctx context.Context) ([]string, error) {
+	var resp models.CategoriesResponse
+	if err := c.getJSON(ctx, "get_cats", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Categories, nil
+}
+
+// GetScripts returns the names of the available post-processing scripts.
+func (c *SabnzbdClient) GetScripts(ctx context.Context) ([]string, error) {
+	var resp models.ScriptsResponse
+	if err := c.getJSON(ctx, "get_scripts", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Scripts, nil
+}
+
+// getJSON queries mode and decodes its response into v.
+func (c *SabnzbdClient) getJSON(ctx context.Context, mode string, params url.Values, v interface{}) error {
+	resp, err := c.GetWithParams(ctx, mode, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("Failed to decode %s response: %w", mode, err)
+	}
+
+	return nil
+}
+
+func setInt(params url.Values, key string, value int) {
+	if value != 0 {
+		params.Set(key, strconv.Itoa(value))
+	}
+}
+
+func setString(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
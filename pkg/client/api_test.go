@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"prometheus-sabnzbd-exporter/pkg/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newFixtureServer serves test_fixtures/<mode>.json, and passes the query of
+// each request to queries.
+func newFixtureServer(t *testing.T, queries chan<- url.Values) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mode := r.URL.Query().Get("mode")
+		if mode == "status" {
+			mode = "fullstatus"
+		}
+
+		body, err := os.ReadFile(filepath.Join("test_fixtures", mode+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+
+		require.NoError(t, err)
+
+		if queries != nil {
+			queries <- r.URL.Query()
+		}
+
+		_, err = w.Write(body)
+		require.NoError(t, err)
+	}))
+}
+
+func TestAPI_Params(t *testing.T) {
+	parameters := []struct {
+		name     string
+		call     func(*SabnzbdClient) error
+		expected url.Values
+	}{
+		{
+			name: "queue",
+			call: func(c *SabnzbdClient) error {
+				_, err := c.Queue(context.Background(), QueueOptions{})
+				return err
+			},
+			expected: url.Values{"mode": {"queue"}},
+		},
+		{
+			name: "queue with options",
+			call: func(c *SabnzbdClient) error {
+				_, err := c.Queue(context.Background(), QueueOptions{
+					Start:    10,
+					Limit:    5,
+					Category: "tv",
+					Search:   "ubuntu",
+					NzoIDs:   []string{"SABnzbd_nzo_1", "SABnzbd_nzo_2"},
+				})
+				return err
+			},
+			expected: url.Values{
+				"mode":    {"queue"},
+				"start":   {"10"},
+				"limit":   {"5"},
+				"cat":     {"tv"},
+				"search":  {"ubuntu"},
+				"nzo_ids": {"SABnzbd_nzo_1,SABnzbd_nzo_2"},
+			},
+		},
+		{
+			name: "history with options",
+			call: func(c *SabnzbdClient) error {
+				_, err := c.History(context.Background(), HistoryOptions{
+					Limit:      100,
+					Category:   "movies",
+					FailedOnly: true,
+				})
+				return err
+			},
+			expected: url.Values{
+				"mode":        {"history"},
+				"limit":       {"100"},
+				"cat":         {"movies"},
+				"failed_only": {"1"},
+			},
+		},
+		{
+			name: "fullstatus with options",
+			call: func(c *SabnzbdClient) error {
+				_, err := c.FullStatus(context.Background(), FullStatusOptions{
+					SkipDashboard:        true,
+					CalculatePerformance: true,
+				})
+				return err
+			},
+			expected: url.Values{
+				"mode":                  {"fullstatus"},
+				"skip_dashboard":        {"1"},
+				"calculate_performance": {"1"},
+			},
+		},
+		{
+			name: "get_config section",
+			call: func(c *SabnzbdClient) error {
+				_, err := c.GetConfig(context.Background(), "misc")
+				return err
+			},
+			expected: url.Values{"mode": {"get_config"}, "section": {"misc"}},
+		},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			queries := make(chan url.Values, 1)
+
+			ts := newFixtureServer(t, queries)
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123")
+			require.NoError(err)
+			require.NoError(tt.call(client))
+
+			query := <-queries
+			query.Del("apikey")
+			query.Del("output")
+			require.Equal(tt.expected, query)
+		})
+	}
+}
+
+func TestAPI_Decodes(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ts := newFixtureServer(t, nil)
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123")
+	require.NoError(err)
+
+	queue, err := client.Queue(ctx, QueueOptions{})
+	require.NoError(err)
+	require.Equal("3.7.2", queue.Queue.Version)
+
+	history, err := client.History(ctx, HistoryOptions{})
+	require.NoError(err)
+	require.Equal(2, history.History.NoofSlots)
+	require.Len(history.History.Slots, 2)
+	require.Equal("Completed", history.History.Slots[0].Status)
+	require.EqualValues(2436906376, history.History.Slots[0].Bytes)
+	require.EqualValues(40, history.History.Slots[0].PostprocTime)
+	require.Equal("Aborted, cannot be completed", history.History.Slots[1].FailMessage)
+
+	stats, err := client.ServerStats(ctx)
+	require.NoError(err)
+	require.EqualValues(5869995742788, stats.Total)
+	require.Contains(stats.Servers, "server1.example.tld")
+
+	warnings, err := client.Warnings(ctx)
+	require.NoError(err)
+	require.Len(warnings.Warnings, 2)
+	require.Equal("ERROR", warnings.Warnings[1].Type)
+	require.EqualValues(1672531260, warnings.Warnings[1].Time)
+
+	for name, get := range map[string]func() (*models.StatusResponse, error){
+		"status": func() (*models.StatusResponse, error) { return client.Status(ctx) },
+		"fullstatus": func() (*models.StatusResponse, error) {
+			return client.FullStatus(ctx, FullStatusOptions{CalculatePerformance: true})
+		},
+	} {
+		resp, err := get()
+		require.NoError(err, name)
+
+		status := resp.Status
+		require.Equal(359071.0, status.Pystone, name)
+		require.Equal("0.58 | 0.56 | 0.55 | V=1053M R=207M", status.LoadAvg, name)
+		require.Nil(status.IPv6, name)
+		require.Len(status.Servers, 1, name)
+		require.Equal("news.example.com", status.Servers[0].ServerName, name)
+		require.Equal(8, status.Servers[0].ServerActiveConn, name)
+	}
+
+	version, err := client.Version(ctx)
+	require.NoError(err)
+	require.Equal("3.7.2", version)
+
+	config, err := client.GetConfig(ctx, "")
+	require.NoError(err)
+
+	var misc struct {
+		DownloadDir string `json:"download_dir"`
+	}
+
+	require.NoError(json.Unmarshal(config.Config["misc"], &misc))
+	require.Equal("/downloads/incomplete", misc.DownloadDir)
+
+	cats, err := client.GetCats(ctx)
+	require.NoError(err)
+	require.Equal([]string{"*", "movies", "software", "tv"}, cats)
+
+	scripts, err := client.GetScripts(ctx)
+	require.NoError(err)
+	require.Equal([]string{"None", "notify.py"}, scripts)
+}
+
+func TestAPI_DecodeError(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"queue": "not an object"}`))
+		require.NoError(err)
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123")
+	require.NoError(err)
+
+	_, err = client.Queue(context.Background(), QueueOptions{})
+
+	var unmarshalErr *json.UnmarshalTypeError
+	require.True(errors.As(err, &unmarshalErr))
+	require.Contains(err.Error(), "Failed to decode queue response")
+}
+
+func TestGet_MaxResponseSize(t *testing.T) {
+	require := require.New(t)
+
+	body := `{"version": "` + strings.Repeat("a", 100) + `"}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(body))
+		require.NoError(err)
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123", WithMaxResponseSize(int64(len(body))))
+	require.NoError(err)
+
+	_, err = client.Version(context.Background())
+	require.NoError(err)
+
+	client, err = NewSabnzbdClient(ts.URL, "abc123", WithMaxResponseSize(int64(len(body)-1)))
+	require.NoError(err)
+
+	_, err = client.Version(context.Background())
+	require.ErrorIs(err, ErrResponseTooLarge)
+}
@@ -0,0 +1,228 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"prometheus-sabnzbd-exporter/internal/redact"
+	"prometheus-sabnzbd-exporter/pkg/secret"
+
+	"github.com/rs/zerolog/log"
+)
+
+var BASE_URI_PATH = "/sabnzbd/api"
+
+// DEFAULT_MAX_RESPONSE_SIZE caps the size of responses read from SabnzbD, so
+// that a misbehaving server or proxy can't exhaust the exporter's memory.
+var DEFAULT_MAX_RESPONSE_SIZE int64 = 16 << 20
+
+type SabnzbdClient struct {
+	baseURL   *url.URL
+	apiPath   string
+	client    *http.Client
+	retry     RetryPolicy
+	onRetry   func(mode, reason string)
+	tlsConfig *tls.Config
+	apiKey    secret.Secret
+	auth      Auth
+
+	maxResponseSize int64
+
+	// baseURI is the api's url, resolved lazily when discovering the api path.
+	lock    sync.Mutex
+	baseURI *url.URL
+}
+
+type Option func(*SabnzbdClient)
+
+// WithRetryPolicy overrides DEFAULT_RETRY_POLICY.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *SabnzbdClient) {
+		c.retry = policy
+	}
+}
+
+// WithRetryHook sets a function called before every retry of a request.
+func WithRetryHook(fn func(mode, reason string)) Option {
+	return func(c *SabnzbdClient) {
+		c.onRetry = fn
+	}
+}
+
+// WithMaxResponseSize overrides DEFAULT_MAX_RESPONSE_SIZE. Larger responses
+// fail with ErrResponseTooLarge.
+func WithMaxResponseSize(size int64) Option {
+	return func(c *SabnzbdClient) {
+		c.maxResponseSize = size
+	}
+}
+
+func NewSabnzbdClient(baseURL, apiKey string, opts ...Option) (*SabnzbdClient, error) {
+	var baseURI *url.URL
+
+	baseURI, err := url.Parse(baseURL)
+	if err != nil || baseURI.Host == "" {
+		log.Warn().
+			Str("baseURL", redact.URL(baseURL)).
+			Msg("baseURL is not a valid URL, trying to parse as host:port")
+
+		var repErr error
+
+		baseURI, repErr = url.Parse("http://" + baseURL)
+		if repErr != nil || baseURI.Host == "" {
+			log.Error().
+				Err(repErr).
+				Str("baseURL", redact.URL(baseURL)).
+				Msg("baseURL is not a valid URL or host:port")
+
+			return nil, redact.Error(fmt.Errorf("baseURL is not a valid URL or host:port (%s) %w", baseURL, err))
+		}
+	}
+
+	c := &SabnzbdClient{
+		baseURL: baseURI,
+		apiPath: BASE_URI_PATH,
+		apiKey:  secret.Static(apiKey),
+		retry:   DEFAULT_RETRY_POLICY,
+
+		maxResponseSize: DEFAULT_MAX_RESPONSE_SIZE,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.apiPath != API_PATH_AUTO {
+		c.baseURI = baseURI.JoinPath(c.apiPath)
+	}
+
+	inner := http.DefaultTransport
+	if c.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.tlsConfig
+		inner = transport
+	}
+
+	c.client = &http.Client{
+		Transport: &SabnzbdTransport{
+			inner:  inner,
+			apiKey: c.apiKey,
+			auth:   c.auth,
+		},
+	}
+
+	return c, nil
+}
+
+// Get queries the given api mode, retrying transient failures according to
+// the client's RetryPolicy, and failing once ctx is done. Returned errors are
+// redacted, as they'd otherwise contain the api key. The body is read in full
+// before Get returns, so closing it is optional. Prefer the typed methods,
+// e.g. Queue, which decode the response.
+func (c *SabnzbdClient) Get(ctx context.Context, mode string) (*http.Response, error) {
+	return c.GetWithParams(ctx, mode, nil)
+}
+
+// GetWithParams is Get with additional query parameters for the mode, e.g.
+// start & limit.
+func (c *SabnzbdClient) GetWithParams(ctx context.Context, mode string, params url.Values) (*http.Response, error) {
+	resp, err := c.getWithRetries(ctx, mode, params)
+	return resp, redact.Error(err)
+}
+
+func (c *SabnzbdClient) getWithRetries(ctx context.Context, mode string, params url.Values) (*http.Response, error) {
+	uri, err := c.apiURI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.get(ctx, uri, mode, params)
+
+		reason, retry := c.retry.retryReason(ctx, err)
+		if !retry || attempt >= c.retry.MaxAttempts {
+			return resp, err
+		}
+
+		backoff := c.retry.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return resp, err
+		}
+
+		if c.onRetry != nil {
+			c.onRetry(mode, reason)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *SabnzbdClient) get(ctx context.Context, uri *url.URL, mode string, params url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	for key, values := range params {
+		for _, v := range values {
+			q.Add(key, v)
+		}
+	}
+
+	q.Set("mode", mode)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, wrapTimeout(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &StatusError{Code: resp.StatusCode}
+	}
+
+	// SabnzbD reports most errors with a 200, so the body has to be inspected
+	// before handing it on.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseSize+1))
+	if err != nil {
+		return nil, wrapTimeout(err)
+	}
+
+	if int64(len(body)) > c.maxResponseSize {
+		return nil, fmt.Errorf("%w: mode %s exceeds %d bytes", ErrResponseTooLarge, mode, c.maxResponseSize)
+	}
+
+	if err := checkAPIError(mode, body); err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+func wrapTimeout(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %s", ErrTimeout, err)
+	}
+
+	return err
+}
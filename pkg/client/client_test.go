@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"prometheus-sabnzbd-exporter/pkg/secret"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+}
+
+func TestNewClient(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal("/sabnzbd/api", r.URL.Path)
+		require.Equal("abc123", r.URL.Query().Get("apikey"))
+		require.Equal("json", r.URL.Query().Get("output"))
+		require.Equal("queue", r.URL.Query().Get("mode"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123")
+	require.NoError(err)
+	require.NotNil(client)
+	_, err = client.Get(context.Background(), "queue")
+	require.NoError(err)
+}
+
+func TestNewClientWithInvalidURL(t *testing.T) {
+	require := require.New(t)
+
+	client, err := NewSabnzbdClient("", "abc123")
+	require.Error(err)
+	require.Nil(client)
+}
+
+func TestGet_GoodStatusCodes(t *testing.T) {
+	require := require.New(t)
+	parameters := []int{
+		http.StatusOK,
+		http.StatusCreated,
+		http.StatusAccepted,
+	}
+
+	for _, parameter := range parameters {
+		t.Run(fmt.Sprintf("%d", parameter), func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(parameter)
+			}))
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123")
+			require.NoError(err)
+			require.NotNil(client)
+			resp, err := client.Get(context.Background(), "queue")
+			require.NoError(err)
+			require.NotNil(resp)
+		})
+	}
+}
+
+func TestGet_BadStatusCodes(t *testing.T) {
+	require := require.New(t)
+	parameters := []int{
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusNotFound,
+		http.StatusMethodNotAllowed,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+	}
+
+	for _, parameter := range parameters {
+		t.Run(fmt.Sprintf("%d", parameter), func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(parameter)
+			}))
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123")
+			require.NoError(err)
+			require.NotNil(client)
+			_, err = client.Get(context.Background(), "queue")
+			require.Error(err)
+
+			var statusErr *StatusError
+			require.True(errors.As(err, &statusErr))
+			require.Equal(parameter, statusErr.Code)
+		})
+	}
+}
+
+func TestGet_Timeout(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123")
+	require.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.Get(ctx, "queue")
+	require.ErrorIs(err, ErrTimeout)
+	require.Less(time.Since(start), time.Second)
+}
+
+func TestGet_Canceled(t *testing.T) {
+	require := require.New(t)
+
+	client, err := NewSabnzbdClient("http://localhost:8080", "abc123")
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Get(ctx, "queue")
+	require.Error(err)
+	require.NotErrorIs(err, ErrTimeout)
+}
+
+func TestGet_APIErrors(t *testing.T) {
+	parameters := []struct {
+		name     string
+		body     string
+		expected error
+	}{
+		{"incorrect api key", `{"status": false, "error": "API Key Incorrect"}`, ErrAuth},
+		{"missing api key", `{"status": false, "error": "API Key Required"}`, ErrAuth},
+		{"nzb key", `{"status": false, "error": "Access denied"}`, ErrNotAllowed},
+		{"unknown mode", `{"status": false, "error": "not implemented"}`, ErrUnknownMode},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(tt.body))
+				require.NoError(err)
+			}))
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123")
+			require.NoError(err)
+
+			_, err = client.Get(context.Background(), "queue")
+			require.ErrorIs(err, tt.expected)
+
+			var apiErr *APIError
+			require.True(errors.As(err, &apiErr))
+			require.Equal("queue", apiErr.Mode)
+		})
+	}
+}
+
+func TestGet_UnrecognizedAPIError(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"status": false, "error": "something broke"}`))
+		require.NoError(err)
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123")
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+
+	var apiErr *APIError
+	require.True(errors.As(err, &apiErr))
+	require.Equal("something broke", apiErr.Message)
+	require.Nil(errors.Unwrap(apiErr))
+}
+
+func TestGet_PassesThroughSuccessfulBodies(t *testing.T) {
+	parameters := []string{
+		`{"queue": {"status": "Idle"}}`,
+		`{"status": true}`,
+		`["not", "an", "object"]`,
+		`not json`,
+	}
+
+	for _, body := range parameters {
+		t.Run(body, func(t *testing.T) {
+			require := require.New(t)
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(body))
+				require.NoError(err)
+			}))
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123")
+			require.NoError(err)
+
+			resp, err := client.Get(context.Background(), "queue")
+			require.NoError(err)
+			defer resp.Body.Close()
+
+			read, err := io.ReadAll(resp.Body)
+			require.NoError(err)
+			require.Equal(body, string(read))
+		})
+	}
+}
+
+func TestGet_AuthStatusCodes(t *testing.T) {
+	parameters := []struct {
+		code     int
+		expected error
+	}{
+		{http.StatusUnauthorized, ErrAuth},
+		{http.StatusForbidden, ErrNotAllowed},
+	}
+
+	for _, tt := range parameters {
+		t.Run(fmt.Sprintf("%d", tt.code), func(t *testing.T) {
+			require := require.New(t)
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+			}))
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123")
+			require.NoError(err)
+
+			_, err = client.Get(context.Background(), "queue")
+			require.ErrorIs(err, tt.expected)
+		})
+	}
+}
+
+func TestGet_RedactsErrors(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(err)
+	u.User = url.UserPassword("user", "hunter2")
+
+	client, err := NewSabnzbdClient(u.String(), "s3cretapikey",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithAuth(Auth{BearerToken: secret.Static("s3cretbearer")}),
+	)
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.Error(err)
+	require.Contains(err.Error(), "user:REDACTED@")
+	require.NotContains(err.Error(), "s3cretapikey")
+	require.NotContains(err.Error(), "hunter2")
+
+	var urlErr *url.Error
+	require.True(errors.As(err, &urlErr), "redacted errors must keep their type")
+	require.NotContains(urlErr.URL, "hunter2")
+}
+
+func TestGet_RedactsRedirectErrors(t *testing.T) {
+	require := require.New(t)
+
+	// A proxy redirecting to an unreachable host, with the query intact
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:1/sabnzbd/api?"+r.URL.RawQuery, http.StatusFound)
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "s3cretapikey", WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.Error(err)
+	require.Contains(err.Error(), "apikey=REDACTED")
+	require.NotContains(err.Error(), "s3cretapikey")
+}
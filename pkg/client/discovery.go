@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"prometheus-sabnzbd-exporter/internal/redact"
+
+	"github.com/rs/zerolog/log"
+)
+
+// API_PATH_AUTO makes the client discover the api path among
+// API_PATH_CANDIDATES instead of using a fixed one.
+var API_PATH_AUTO = "auto"
+
+// API_PATH_CANDIDATES are the api paths of SabnzbD with its default url_base
+// (/sabnzbd) and with an empty url_base, in the order they're probed.
+var API_PATH_CANDIDATES = []string{BASE_URI_PATH, "/api"}
+
+// ErrNoAPIPath is returned when none of API_PATH_CANDIDATES answered.
+var ErrNoAPIPath = errors.New("no api path answered")
+
+// WithAPIPath sets the path of the api below the base url, or API_PATH_AUTO
+// to discover it. Defaults to BASE_URI_PATH.
+func WithAPIPath(path string) Option {
+	return func(c *SabnzbdClient) {
+		if path != "" {
+			c.apiPath = path
+		}
+	}
+}
+
+// Discover resolves the api path, when configured to discover it. It's called
+// by the first query at the latest, and again on every query until it
+// succeeded.
+func (c *SabnzbdClient) Discover(ctx context.Context) error {
+	_, err := c.apiURI(ctx)
+	return redact.Error(err)
+}
+
+func (c *SabnzbdClient) apiURI(ctx context.Context) (*url.URL, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.baseURI != nil {
+		return c.baseURI, nil
+	}
+
+	var errs []string
+
+	for _, path := range API_PATH_CANDIDATES {
+		uri := c.baseURL.JoinPath(path)
+
+		err := c.probeVersion(ctx, uri)
+		if err == nil {
+			log.Info().
+				Str("baseURL", redact.URL(c.baseURL.String())).
+				Str("apiPath", path).
+				Msg("Discovered SabnzbD api path")
+
+			c.baseURI = uri
+
+			return uri, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %s", path, err))
+	}
+
+	return nil, fmt.Errorf("%w (%s)", ErrNoAPIPath, strings.Join(errs, "; "))
+}
+
+// probeVersion checks whether uri is SabnzbD's api, by querying its version.
+func (c *SabnzbdClient) probeVersion(ctx context.Context, uri *url.URL) error {
+	resp, err := c.get(ctx, uri, "version", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var version struct {
+		Version string `json:"version"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return fmt.Errorf("unexpected response: %w", err)
+	}
+
+	if version.Version == "" {
+		return errors.New("unexpected response: missing version")
+	}
+
+	return nil
+}
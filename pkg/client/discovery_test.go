@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newVersionServer(t *testing.T, apiPath string, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			atomic.AddInt32(requests, 1)
+		}
+
+		if r.URL.Path != apiPath {
+			http.NotFound(w, r)
+			return
+		}
+
+		var err error
+
+		switch r.URL.Query().Get("mode") {
+		case "version":
+			_, err = w.Write([]byte(`{"version": "3.7.2"}`))
+		default:
+			_, err = w.Write([]byte(`{"queue": {}}`))
+		}
+
+		require.NoError(t, err)
+	}))
+}
+
+func TestGet_APIPath(t *testing.T) {
+	parameters := []struct {
+		name    string
+		apiPath string
+		server  string
+	}{
+		{"default", "", "/sabnzbd/api"},
+		{"empty url_base", "/api", "/api"},
+		{"custom url_base", "/downloads/sabnzbd/api", "/downloads/sabnzbd/api"},
+		{"discover default", "auto", "/sabnzbd/api"},
+		{"discover empty url_base", "auto", "/api"},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ts := newVersionServer(t, tt.server, nil)
+			defer ts.Close()
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123", WithAPIPath(tt.apiPath))
+			require.NoError(err)
+
+			resp, err := client.Get(context.Background(), "queue")
+			require.NoError(err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestDiscover_OnlyOnce(t *testing.T) {
+	require := require.New(t)
+
+	var requests int32
+
+	ts := newVersionServer(t, "/api", &requests)
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123", WithAPIPath("auto"))
+	require.NoError(err)
+
+	require.NoError(client.Discover(context.Background()))
+	require.EqualValues(2, atomic.LoadInt32(&requests))
+
+	resp, err := client.Get(context.Background(), "queue")
+	require.NoError(err)
+	resp.Body.Close()
+	require.EqualValues(3, atomic.LoadInt32(&requests))
+}
+
+func TestDiscover_NoAPIPath(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("<html>not sabnzbd</html>"))
+		require.NoError(err)
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123", WithAPIPath("auto"))
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.ErrorIs(err, ErrNoAPIPath)
+
+	// Discovery is retried by later queries
+	require.ErrorIs(client.Discover(context.Background()), ErrNoAPIPath)
+}
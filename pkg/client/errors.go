@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrTimeout is returned when a request didn't complete before its deadline.
+var ErrTimeout = errors.New("request timed out")
+
+// ErrAuth is returned when SabnzbD rejects the api key.
+var ErrAuth = errors.New("authentication failed")
+
+// ErrNotAllowed is returned when the api key may not use the requested mode,
+// e.g. when using the nzb key for anything but adding nzbs.
+var ErrNotAllowed = errors.New("not allowed")
+
+// ErrUnknownMode is returned when SabnzbD doesn't implement the requested mode.
+var ErrUnknownMode = errors.New("unknown mode")
+
+// ErrResponseTooLarge is returned when a response exceeds the client's
+// maximum response size.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// StatusError is returned when SabnzbD responds with an error status code.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	if e.Code >= http.StatusInternalServerError {
+		return fmt.Sprintf("server error: %d", e.Code)
+	}
+
+	return fmt.Sprintf("client error: %d", e.Code)
+}
+
+// Unwrap maps authentication related status codes to ErrAuth and ErrNotAllowed.
+func (e *StatusError) Unwrap() error {
+	switch e.Code {
+	case http.StatusUnauthorized:
+		return ErrAuth
+	case http.StatusForbidden:
+		return ErrNotAllowed
+	default:
+		return nil
+	}
+}
+
+// APIError is returned when SabnzbD answers with an error envelope such as
+// {"status": false, "error": "API Key Incorrect"}, which it does with a 200
+// status code.
+type APIError struct {
+	Mode    string
+	Message string
+	Err     error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error for mode %s: %s", e.Mode, e.Message)
+}
+
+// Unwrap returns ErrAuth, ErrNotAllowed, ErrUnknownMode or nil if the message
+// isn't recognized.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+type errorEnvelope struct {
+	Status *bool  `json:"status"`
+	Error  string `json:"error"`
+}
+
+// checkAPIError returns an *APIError if body is an error envelope. Anything
+// else, including bodies which aren't json, is left to the caller to decode.
+func checkAPIError(mode string, body []byte) error {
+	var envelope errorEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+
+	if envelope.Status == nil || *envelope.Status {
+		return nil
+	}
+
+	return &APIError{
+		Mode:    mode,
+		Message: envelope.Error,
+		Err:     classifyAPIError(envelope.Error),
+	}
+}
+
+func classifyAPIError(message string) error {
+	message = strings.ToLower(message)
+
+	switch {
+	case strings.Contains(message, "api key"), strings.Contains(message, "apikey"):
+		return ErrAuth
+	case strings.Contains(message, "not implemented"), strings.Contains(message, "unknown mode"):
+		return ErrUnknownMode
+	case strings.Contains(message, "access denied"), strings.Contains(message, "not allowed"),
+		strings.Contains(message, "permission"):
+		return ErrNotAllowed
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures how failed requests are retried. Retries never
+// outlive the deadline of the request's context.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first, 1 disables retries
+	BaseBackoff time.Duration // Backoff before the first retry, doubled on every retry
+	MaxBackoff  time.Duration // Upper bound of the backoff
+	Jitter      float64       // Fraction of the backoff which is randomized (0-1)
+	StatusCodes []int         // HTTP status codes which are retried
+}
+
+var DEFAULT_RETRY_POLICY = RetryPolicy{
+	MaxAttempts: 3,
+	BaseBackoff: 100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+	Jitter:      0.2,
+	StatusCodes: []int{502, 503, 504},
+}
+
+var (
+	jitterLock sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns how long to wait before the given retry (1 based).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.BaseBackoff
+	for i := 1; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	jitterLock.Lock()
+	defer jitterLock.Unlock()
+
+	return backoff - time.Duration(p.Jitter*jitterRand.Float64()*float64(backoff))
+}
+
+// retryReason returns why err should be retried, or false if it shouldn't.
+func (p RetryPolicy) retryReason(ctx context.Context, err error) (string, bool) {
+	if err == nil || ctx.Err() != nil {
+		return "", false
+	}
+
+	var (
+		statusErr *StatusError
+		urlErr    *url.Error
+	)
+
+	switch {
+	case errors.As(err, &statusErr):
+		for _, code := range p.StatusCodes {
+			if code == statusErr.Code {
+				return fmt.Sprintf("http_%d", code), true
+			}
+		}
+
+		return "", false
+	case errors.Is(err, ErrTimeout):
+		return "timeout", true
+	case errors.As(err, &urlErr):
+		return "connection", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	require := require.New(t)
+
+	policy := RetryPolicy{
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+	}
+
+	require.Equal(100*time.Millisecond, policy.backoff(1))
+	require.Equal(200*time.Millisecond, policy.backoff(2))
+	require.Equal(400*time.Millisecond, policy.backoff(3))
+	require.Equal(800*time.Millisecond, policy.backoff(4))
+	require.Equal(time.Second, policy.backoff(5))
+	require.Equal(time.Second, policy.backoff(50))
+}
+
+func TestRetryPolicy_BackoffJitter(t *testing.T) {
+	require := require.New(t)
+
+	policy := RetryPolicy{
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		Jitter:      0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		backoff := policy.backoff(2)
+		require.GreaterOrEqual(backoff, 100*time.Millisecond)
+		require.LessOrEqual(backoff, 200*time.Millisecond)
+	}
+}
+
+func newFlakyServer(failures int32, code int) (*httptest.Server, *int32) {
+	var requests int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			w.WriteHeader(code)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})), &requests
+}
+
+func TestGet_RetriesTransientErrors(t *testing.T) {
+	require := require.New(t)
+
+	ts, requests := newFlakyServer(2, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	var reasons []string
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123",
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		}),
+		WithRetryHook(func(mode, reason string) {
+			require.Equal("queue", mode)
+			reasons = append(reasons, reason)
+		}),
+	)
+	require.NoError(err)
+
+	resp, err := client.Get(context.Background(), "queue")
+	require.NoError(err)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	require.Equal(int32(3), atomic.LoadInt32(requests))
+	require.Equal([]string{"http_503", "http_503"}, reasons)
+}
+
+func TestGet_GivesUpAfterMaxAttempts(t *testing.T) {
+	require := require.New(t)
+
+	ts, requests := newFlakyServer(10, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123",
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		}),
+	)
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.Error(err)
+	require.Equal(int32(3), atomic.LoadInt32(requests))
+}
+
+func TestGet_DoesntRetryOtherStatusCodes(t *testing.T) {
+	require := require.New(t)
+
+	ts, requests := newFlakyServer(10, http.StatusNotFound)
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123",
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		}),
+	)
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.Error(err)
+	require.Equal(int32(1), atomic.LoadInt32(requests))
+}
+
+func TestGet_RetriesConnectionErrors(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	var reasons []string
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123",
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 2,
+			BaseBackoff: time.Millisecond,
+		}),
+		WithRetryHook(func(mode, reason string) {
+			reasons = append(reasons, reason)
+		}),
+	)
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.Error(err)
+	require.Equal([]string{"connection"}, reasons)
+}
+
+func TestGet_RetriesStayWithinDeadline(t *testing.T) {
+	require := require.New(t)
+
+	ts, requests := newFlakyServer(10, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123",
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 10,
+			BaseBackoff: time.Second,
+			MaxBackoff:  time.Second,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		}),
+	)
+	require.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.Get(ctx, "queue")
+	require.Error(err)
+	require.Less(time.Since(start), 200*time.Millisecond)
+	require.Equal(int32(1), atomic.LoadInt32(requests))
+}
+
+func TestGet_NoRetriesWithSingleAttempt(t *testing.T) {
+	require := require.New(t)
+
+	ts, requests := newFlakyServer(10, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123", WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.Error(err)
+	require.Equal(int32(1), atomic.LoadInt32(requests))
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCAFile(t *testing.T, ts *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, ca, 0o600))
+
+	return path
+}
+
+func TestGet_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	caFile := writeCAFile(t, ts)
+
+	parameters := []struct {
+		name    string
+		opts    *TLSOptions
+		wantErr bool
+	}{
+		{
+			name:    "untrusted",
+			opts:    nil,
+			wantErr: true,
+		},
+		{
+			name: "custom ca",
+			opts: &TLSOptions{CAFile: caFile},
+		},
+		{
+			name: "server name override",
+			opts: &TLSOptions{CAFile: caFile, ServerName: "example.com"},
+		},
+		{
+			name:    "wrong server name",
+			opts:    &TLSOptions{CAFile: caFile, ServerName: "sabnzbd.invalid"},
+			wantErr: true,
+		},
+		{
+			name: "insecure skip verify",
+			opts: &TLSOptions{InsecureSkipVerify: true},
+		},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var opts []Option
+
+			if tt.opts != nil {
+				cfg, err := NewTLSConfig(*tt.opts)
+				require.NoError(err)
+
+				opts = append(opts, WithTLSConfig(cfg))
+			}
+
+			client, err := NewSabnzbdClient(ts.URL, "abc123", opts...)
+			require.NoError(err)
+
+			_, err = client.Get(context.Background(), "queue")
+			if tt.wantErr {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+		})
+	}
+}
+
+func TestGet_TLSClientCertificate(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Len(r.TLS.PeerCertificates, 1)
+		require.Equal("sabnzbd-exporter-test", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cfg, err := NewTLSConfig(TLSOptions{
+		CAFile:   writeCAFile(t, ts),
+		CertFile: "test_fixtures/client.crt",
+		KeyFile:  "test_fixtures/client.key",
+	})
+	require.NoError(err)
+
+	client, err := NewSabnzbdClient(ts.URL, "abc123", WithTLSConfig(cfg))
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.NoError(err)
+}
+
+func TestNewTLSConfig_Errors(t *testing.T) {
+	parameters := []struct {
+		name string
+		opts TLSOptions
+	}{
+		{"missing ca file", TLSOptions{CAFile: "test_fixtures/missing.crt"}},
+		{"ca file without certificates", TLSOptions{CAFile: "test_fixtures/client.key"}},
+		{"cert without key", TLSOptions{CertFile: "test_fixtures/client.crt"}},
+		{"mismatched key", TLSOptions{CertFile: "test_fixtures/client.crt", KeyFile: "test_fixtures/client.crt"}},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTLSConfig(tt.opts)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"net/http"
+
+	"prometheus-sabnzbd-exporter/pkg/secret"
+)
+
+// SabnzbdTransport adds credentials to every request. Secrets are read on
+// every request, so rotated secrets are picked up right away.
+type SabnzbdTransport struct {
+	apiKey secret.Secret
+	auth   Auth
+	inner  http.RoundTripper
+}
+
+// Auth holds credentials required by a reverse proxy in front of SabnzbD.
+// At most one of basic auth & bearer token may be set.
+type Auth struct {
+	Username    string
+	Password    secret.Secret
+	BearerToken secret.Secret
+	Headers     map[string]secret.Secret
+}
+
+func value(s secret.Secret) string {
+	if s == nil {
+		return ""
+	}
+
+	return s.Value()
+}
+
+func (t *SabnzbdTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	q := req.URL.Query()
+	q.Add("apikey", value(t.apiKey))
+	q.Add("output", "json")
+	req.URL.RawQuery = q.Encode()
+
+	for name, header := range t.auth.Headers {
+		req.Header.Set(name, value(header))
+	}
+
+	switch {
+	case t.auth.Username != "":
+		req.SetBasicAuth(t.auth.Username, value(t.auth.Password))
+	case t.auth.BearerToken != nil:
+		req.Header.Set("Authorization", "Bearer "+value(t.auth.BearerToken))
+	}
+
+	return t.inner.RoundTrip(req)
+}
+
+// WithAPIKey replaces the api key passed to NewSabnzbdClient, e.g. with one
+// read from a file.
+func WithAPIKey(apiKey secret.Secret) Option {
+	return func(c *SabnzbdClient) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithAuth sets credentials sent along with every request.
+func WithAuth(auth Auth) Option {
+	return func(c *SabnzbdClient) {
+		c.auth = auth
+	}
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"prometheus-sabnzbd-exporter/pkg/secret"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRoundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (t testRoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t(req)
+}
+
+func TestRoundTrip(t *testing.T) {
+	require := require.New(t)
+	transport := SabnzbdTransport{
+		apiKey: secret.Static("abc123"),
+		inner: testRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			require.NotNil(req)
+			require.Equal("http://localhost:8080/sabnzbd/api?apikey=abc123&output=json", req.URL.String())
+			return &http.Response{
+				StatusCode: 200,
+				Body:       http.NoBody,
+			}, nil
+		}),
+	}
+	req, err := http.NewRequest("GET", "http://localhost:8080/sabnzbd/api", nil)
+	require.NotNil(req)
+	require.NoError(err)
+	resp, err := transport.RoundTrip(req)
+	require.NotNil(resp)
+	require.NoError(err)
+}
+
+func TestRoundTrip_Auth(t *testing.T) {
+	parameters := []struct {
+		name     string
+		auth     Auth
+		expected http.Header
+	}{
+		{
+			name:     "none",
+			auth:     Auth{},
+			expected: http.Header{},
+		},
+		{
+			name: "basic auth",
+			auth: Auth{Username: "user", Password: secret.Static("secret")},
+			expected: http.Header{
+				"Authorization": {"Basic dXNlcjpzZWNyZXQ="},
+			},
+		},
+		{
+			name: "bearer token",
+			auth: Auth{BearerToken: secret.Static("token")},
+			expected: http.Header{
+				"Authorization": {"Bearer token"},
+			},
+		},
+		{
+			name: "headers",
+			auth: Auth{Headers: map[string]secret.Secret{"X-Auth-Token": secret.Static("token"), "remote-user": secret.Static("exporter")}},
+			expected: http.Header{
+				"X-Auth-Token": {"token"},
+				"Remote-User":  {"exporter"},
+			},
+		},
+		{
+			name: "basic auth overrides authorization header",
+			auth: Auth{Username: "user", Password: secret.Static("secret"), Headers: map[string]secret.Secret{"Authorization": secret.Static("other")}},
+			expected: http.Header{
+				"Authorization": {"Basic dXNlcjpzZWNyZXQ="},
+			},
+		},
+	}
+
+	for _, tt := range parameters {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			transport := SabnzbdTransport{
+				apiKey: secret.Static("abc123"),
+				auth:   tt.auth,
+				inner: testRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+					require.Equal(tt.expected, req.Header)
+					return &http.Response{
+						StatusCode: 200,
+						Body:       http.NoBody,
+					}, nil
+				}),
+			}
+
+			req, err := http.NewRequest("GET", "http://localhost:8080/sabnzbd/api", nil)
+			require.NoError(err)
+
+			_, err = transport.RoundTrip(req)
+			require.NoError(err)
+			require.Empty(req.Header, "the original request must not be modified")
+		})
+	}
+}
+
+func TestGet_RotatedAPIKey(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "api_key")
+	require.NoError(os.WriteFile(path, []byte("abc123\n"), 0o600))
+
+	apiKey, err := secret.FromFile(path)
+	require.NoError(err)
+
+	keys := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.URL.Query().Get("apikey")
+	}))
+	defer ts.Close()
+
+	client, err := NewSabnzbdClient(ts.URL, "", WithAPIKey(apiKey))
+	require.NoError(err)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.NoError(err)
+	require.Equal("abc123", <-keys)
+
+	require.NoError(os.WriteFile(path, []byte("def456\n"), 0o600))
+	require.Eventually(func() bool {
+		return apiKey.Value() == "def456"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, err = client.Get(context.Background(), "queue")
+	require.NoError(err)
+	require.Equal("def456", <-keys)
+}
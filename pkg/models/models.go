@@ -0,0 +1,503 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Status int
+
+const (
+	KB = 1024
+	MB = 1024 * KB
+)
+
+const (
+	UNKNOWN Status = iota
+	IDLE
+	PAUSED
+	DOWNLOADING
+)
+
+func (s Status) Float64() float64 {
+	return float64(s)
+}
+func (s Status) String() string {
+	switch s {
+	case IDLE:
+		return "Idle"
+	case PAUSED:
+		return "Paused"
+	case DOWNLOADING:
+		return "Downloading"
+	default:
+		return "Unknown"
+	}
+}
+
+func StatusFromString(s string) Status {
+	switch s {
+	case "Idle":
+		return IDLE
+	case "Paused":
+		return PAUSED
+	case "Downloading":
+		return DOWNLOADING
+	default:
+		return UNKNOWN
+	}
+}
+
+type ServerStat struct {
+	Total           int            // Total Data Downloaded in bytes
+	Day             int            // Data Downloaded today in bytes
+	Week            int            // Data Downloaded this week in bytes
+	Month           int            // Data Downloaded this month in bytes
+	Daily           map[string]int // Data Downloaded per day in bytes (YYYY-MM-DD -> bytes)
+	ArticlesTried   int            // Number of Articles Tried
+	ArticlesSuccess int            // Number of Articles Successfully Downloaded
+	DayParsed       string         // Last Date Parsed
+
+	ArticlesTriedDaily   map[string]int // Number of Articles Tried per day (YYYY-MM-DD -> count)
+	ArticlesSuccessDaily map[string]int // Number of Articles Successfully Downloaded per day (YYYY-MM-DD -> count)
+}
+
+type ServerStats struct {
+	Total   int // Total Data Downloaded in bytes
+	Day     int // Data Downloaded today in bytes
+	Week    int // Data Downloaded this week in bytes
+	Month   int // Data Downloaded this month in bytes
+	Servers map[string]ServerStat
+}
+
+func NewServerStatsFromResponse(response ServerStatsResponse) *ServerStats {
+	ret := &ServerStats{
+		Total:   response.Total,
+		Day:     response.Day,
+		Week:    response.Week,
+		Month:   response.Month,
+		Servers: make(map[string]ServerStat),
+	}
+
+	for name, stats := range response.Servers {
+		d, tried := latestStat(stats.ArticlesTried)
+		_, success := latestStat(stats.ArticlesSuccess)
+		ret.Servers[name] = ServerStat{
+			Total:           stats.Total,
+			Day:             stats.Day,
+			Week:            stats.Week,
+			Month:           stats.Month,
+			Daily:           stats.Daily,
+			ArticlesTried:   tried,
+			ArticlesSuccess: success,
+			DayParsed:       d,
+
+			ArticlesTriedDaily:   stats.ArticlesTried,
+			ArticlesSuccessDaily: stats.ArticlesSuccess,
+		}
+	}
+
+	return ret
+}
+
+type QueueStats struct {
+	Version                    string        // Sabnzbd Version
+	Paused                     bool          // Is the sabnzbd queue globally paused?
+	PausedAll                  bool          // Paused All actions which causes disk activity
+	PauseDuration              time.Duration // Duration sabnzbd will remain paused
+	DownloadDirDiskspaceUsed   float64       // Download Directory Used in bytes
+	DownloadDirDiskspaceTotal  float64       // Download Directory Total in bytes
+	CompletedDirDiskspaceUsed  float64       // Completed Directory Used in bytes
+	CompletedDirDiskspaceTotal float64       // Completed Directory Total in bytes
+	SpeedLimit                 float64       // The Speed Limit set as a percentage of configured line speed
+	SpeedLimitAbs              float64       // The Speed Limit set in B/s
+	HaveSpeedLimitAbs          bool          // Does sabnzbd report the Speed Limit in B/s?
+	HaveWarnings               float64       // Number of Warnings present
+	Quota                      float64       // Total Quota configured Bytes
+	HaveQuota                  bool          // Is a Periodic Quota set for Sabnzbd?
+	RemainingQuota             float64       // Quota Remaining Bytes
+	CacheArt                   float64       // Number of Articles in Cache
+	CacheSize                  float64       // Size of Cache in bytes
+	Speed                      float64       // Float String representing bps
+	RemainingSize              float64       // Bytes left to download in queue
+	Size                       float64       // total bytes represented by queue
+	ItemsInQueue               float64       // Total number of items in queue
+	Status                     Status        // Status of sabnzbd (1 = Idle, 2 = Paused, 3 = Downloading)
+	TimeEstimate               time.Duration // Estimated time remaining to download queue
+	Slots                      []QueueSlot   // Jobs in the queue
+}
+
+// QueueSlot is a job in sabnzbd's queue
+type QueueSlot struct {
+	Index         int           // Position of the job in the queue (0 based)
+	ID            string        // nzo_id of the job
+	Name          string        // Name of the job
+	Status        string        // Status of the job (Queued, Paused, Downloading, Fetching, Propagating, ...)
+	Category      string        // Category of the job
+	Priority      string        // Priority of the job (Force, High, Normal, Low, ...)
+	Size          float64       // Size of the job in bytes
+	RemainingSize float64       // Bytes left to download
+	Progress      float64       // Fraction of the job downloaded (0-1)
+	TimeEstimate  time.Duration // Estimated time remaining to download the job
+}
+
+func newQueueSlotFromResponse(slot QueueSlotResponse) (QueueSlot, error) {
+	var err error
+
+	size, err := parseFloat(slot.MB, err)
+	remainingSize, err := parseFloat(slot.MBLeft, err)
+	percentage, err := parseFloat(slot.Percentage, err)
+	timeLeft, err := parseDuration(slot.TimeLeft, err)
+
+	if err != nil {
+		return QueueSlot{}, fmt.Errorf("Error parsing queue slot %s: %w", slot.NzoID, err)
+	}
+
+	return QueueSlot{
+		Index:         slot.Index,
+		ID:            slot.NzoID,
+		Name:          slot.Filename,
+		Status:        slot.Status,
+		Category:      slot.Category,
+		Priority:      slot.Priority,
+		Size:          size * MB,
+		RemainingSize: remainingSize * MB,
+		Progress:      percentage / 100,
+		TimeEstimate:  timeLeft,
+	}, nil
+}
+
+func NewQueueStatsFromResponse(response QueueResponse) (QueueStats, error) {
+	var err error
+
+	queue := response.Queue
+	pauseDuration, err := parseDuration(queue.PauseInt, err)
+	downloadDirDiskspaceUsed, err := parseFloat(queue.Diskspace1, err)
+	downloadDirDiskspaceTotal, err := parseFloat(queue.DiskspaceTotal1, err)
+	completedDirDiskspaceUsed, err := parseFloat(queue.Diskspace2, err)
+	completedDirDiskspaceTotal, err := parseFloat(queue.DiskspaceTotal2, err)
+	leftQuota, err := parseSize(queue.LeftQuota, err)
+	cacheArt, err := parseSize(queue.CacheArt, err)
+	cacheSize, err := parseSize(queue.CacheSize, err)
+	speed, err := parseFloat(queue.KBPerSec, err)
+	remainingSize, err := parseFloat(queue.MBLeft, err)
+	size, err := parseFloat(queue.MB, err)
+	quota, err := parseSize(queue.Quota, err)
+	speedLimit, err := parseSize(queue.Speedlimit, err)
+	speedLimitAbs, err := parseSize(queue.SpeedlimitAbs, err)
+	haveWarnings, err := parseFloat(queue.HaveWarnings, err)
+	timeLeft, err := parseDuration(queue.TimeLeft, err)
+
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("Error parsing queue stats: %s", err)
+	}
+
+	slots := make([]QueueSlot, 0, len(queue.Slots))
+
+	for _, slotResponse := range queue.Slots {
+		slot, err := newQueueSlotFromResponse(slotResponse)
+		if err != nil {
+			return QueueStats{}, err
+		}
+
+		slots = append(slots, slot)
+	}
+
+	return QueueStats{
+		Version:                    queue.Version,
+		Paused:                     queue.Paused,
+		PausedAll:                  queue.PausedAll,
+		PauseDuration:              pauseDuration,
+		DownloadDirDiskspaceUsed:   downloadDirDiskspaceUsed * MB,
+		DownloadDirDiskspaceTotal:  downloadDirDiskspaceTotal * MB,
+		CompletedDirDiskspaceUsed:  completedDirDiskspaceUsed * MB,
+		CompletedDirDiskspaceTotal: completedDirDiskspaceTotal * MB,
+		SpeedLimit:                 speedLimit,
+		SpeedLimitAbs:              speedLimitAbs,
+		HaveSpeedLimitAbs:          queue.SpeedlimitAbs != "",
+		HaveWarnings:               haveWarnings,
+		Quota:                      quota,
+		HaveQuota:                  queue.HaveQuota,
+		RemainingQuota:             leftQuota,
+		CacheArt:                   cacheArt,
+		CacheSize:                  cacheSize,
+		Speed:                      speed * KB,
+		RemainingSize:              remainingSize * MB,
+		Size:                       size * MB,
+		ItemsInQueue:               float64(queue.NoofSlotsTotal),
+		Status:                     StatusFromString(queue.Status),
+		TimeEstimate:               timeLeft,
+		Slots:                      slots,
+	}, nil
+}
+
+// latestStat gets the most recent date's value from a map of dates to values
+func latestStat(m map[string]int) (string, int) {
+	// Servers which haven't downloaded anything yet have no stats
+	if len(m) == 0 {
+		return "", 0
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	key := keys[len(keys)-1]
+
+	return key, m[key]
+}
+
+// parseFloat is a monad version of strconv.ParseFloat
+func parseFloat(f string, prevErr error) (float64, error) {
+	if prevErr != nil {
+		return 0, prevErr
+	}
+
+	if f == "" {
+		return 0, nil
+	}
+
+	ret, err := strconv.ParseFloat(f, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return ret, nil
+}
+
+// parseSize is a monad which parses a size string in the format of "123.45 KB" or "123.45"
+func parseSize(sz string, prevErr error) (float64, error) {
+	if prevErr != nil {
+		return 0, prevErr
+	}
+
+	fields := strings.Fields(strings.TrimSpace(sz))
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	if len(fields) > 2 {
+		return 0, fmt.Errorf("Invalid size: %s", sz)
+	}
+
+	ret, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(fields) == 1 {
+		return ret, nil
+	}
+
+	switch fields[1] {
+	case "B":
+		return ret, nil
+	case "KB", "K":
+		return ret * 1024, nil
+	case "MB", "M":
+		return ret * 1024 * 1024, nil
+	case "GB", "G":
+		return ret * 1024 * 1024 * 1024, nil
+	case "TB", "T":
+		return ret * 1024 * 1024 * 1024 * 1024, nil
+	case "PB", "P":
+		return ret * 1024 * 1024 * 1024 * 1024 * 1024, nil
+	default:
+		return 0, fmt.Errorf("Invalid size suffix: %s", sz)
+	}
+}
+
+// parseDuration is a monad which parses a duration string in the format of "HH:MM:SS" or "MM:SS"
+func parseDuration(s string, prevErr error) (time.Duration, error) {
+	if prevErr != nil {
+		return 0, prevErr
+	}
+
+	if s == "" {
+		return 0, nil
+	}
+
+	fields := strings.Split(strings.TrimSpace(s), ":")
+	if len(fields) < 1 || len(fields) > 4 {
+		return 0, fmt.Errorf("Invalid duration: %s", s)
+	}
+
+	intFields := make([]int, len(fields))
+
+	for i, f := range fields {
+		var err error
+		// Reverse the order of the fields
+		intFields[len(intFields)-1-i], err = strconv.Atoi(f)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid integer in duration: %s: %w", f, err)
+		}
+	}
+
+	ret := time.Duration(intFields[0]) * time.Second
+
+	fieldCount := len(intFields)
+	if fieldCount > 1 {
+		ret += time.Duration(intFields[1]) * time.Minute
+	}
+
+	if fieldCount > 2 {
+		ret += time.Duration(intFields[2]) * time.Hour
+	}
+
+	if fieldCount > 3 {
+		ret += time.Duration(intFields[3]) * 24 * time.Hour
+	}
+
+	return ret, nil
+}
+
+// HistoryJob is a job in sabnzbd's history
+type HistoryJob struct {
+	ID                 string        // nzo_id of the job
+	Status             string        // Status of the job (Completed, Failed, Extracting, ...)
+	Category           string        // Category of the job
+	DownloadedBytes    int64         // Bytes downloaded for the job
+	DownloadTime       time.Duration // Time spent downloading
+	PostProcessingTime time.Duration // Time spent post-processing
+	Completed          time.Time     // Time the job was completed, zero while it's post-processed
+}
+
+// Finished returns whether the job completed or failed, rather than still
+// being post-processed.
+func (j HistoryJob) Finished() bool {
+	return j.Status == "Completed" || j.Status == "Failed"
+}
+
+type HistoryStats struct {
+	Jobs []HistoryJob // Jobs in the history, latest first
+}
+
+func NewHistoryStatsFromResponse(response HistoryResponse) HistoryStats {
+	ret := HistoryStats{
+		Jobs: make([]HistoryJob, 0, len(response.History.Slots)),
+	}
+
+	for _, slot := range response.History.Slots {
+		var completed time.Time
+		if slot.Completed > 0 {
+			completed = time.Unix(slot.Completed, 0)
+		}
+
+		ret.Jobs = append(ret.Jobs, HistoryJob{
+			ID:                 slot.NzoID,
+			Status:             slot.Status,
+			Category:           slot.Category,
+			DownloadedBytes:    slot.Downloaded,
+			DownloadTime:       time.Duration(slot.DownloadTime) * time.Second,
+			PostProcessingTime: time.Duration(slot.PostprocTime) * time.Second,
+			Completed:          completed,
+		})
+	}
+
+	return ret
+}
+
+// Warning is a warning or error logged by sabnzbd
+type Warning struct {
+	Level string    // Level of the warning, lower case (warning, error)
+	Text  string    // Text of the warning
+	Time  time.Time // Time the warning was logged
+}
+
+func NewWarningsFromResponse(response WarningsResponse) []Warning {
+	ret := make([]Warning, 0, len(response.Warnings))
+
+	for _, w := range response.Warnings {
+		ret = append(ret, Warning{
+			Level: strings.ToLower(w.Type),
+			Text:  w.Text,
+			Time:  time.Unix(w.Time, 0),
+		})
+	}
+
+	return ret
+}
+
+// FullStatus is the status of sabnzbd's system and news servers
+type FullStatus struct {
+	LoadAverages      []float64      // 1, 5 & 15 minute load averages, empty when sabnzbd doesn't report them
+	Pystone           float64        // Pystone benchmark score of the cpu
+	DownloadDirSpeed  float64        // Write speed of the download directory in bytes/s, 0 until measured
+	CompleteDirSpeed  float64        // Write speed of the complete directory in bytes/s, 0 until measured
+	InternetBandwidth float64        // Internet bandwidth in bytes/s, 0 until measured
+	Servers           []ServerStatus // Status of the news servers
+}
+
+// ServerStatus is the connection state of a news server
+type ServerStatus struct {
+	Name              string // Display name of the news server
+	Enabled           bool   // Is the server enabled?
+	ActiveConnections int    // Number of open connections
+	MaxConnections    int    // Number of configured connections
+	Priority          int    // Priority of the server (0 is highest)
+	Error             string // Error of the server, or the warning reported instead of its connections
+}
+
+func NewFullStatusFromResponse(response StatusResponse) (FullStatus, error) {
+	status := response.Status
+
+	loadAverages, err := parseLoadAverages(status.LoadAvg)
+	if err != nil {
+		return FullStatus{}, fmt.Errorf("Error parsing load averages: %w", err)
+	}
+
+	ret := FullStatus{
+		LoadAverages:      loadAverages,
+		Pystone:           status.Pystone,
+		DownloadDirSpeed:  status.DownloadDirSpeed * MB,
+		CompleteDirSpeed:  status.CompleteDirSpeed * MB,
+		InternetBandwidth: status.InternetBandwidth * MB,
+		Servers:           make([]ServerStatus, 0, len(status.Servers)),
+	}
+
+	for _, server := range status.Servers {
+		serverError := server.ServerError
+		if serverError == "" {
+			serverError = server.ServerWarning
+		}
+
+		ret.Servers = append(ret.Servers, ServerStatus{
+			Name:              server.ServerName,
+			Enabled:           server.ServerActive,
+			ActiveConnections: server.ServerActiveConn,
+			MaxConnections:    server.ServerTotalConn,
+			Priority:          server.ServerPriority,
+			Error:             serverError,
+		})
+	}
+
+	return ret, nil
+}
+
+// parseLoadAverages parses the 1, 5 & 15 minute load averages at the start of
+// sabnzbd's loadavg, e.g. "0.58 | 0.56 | 0.55 | V=1053M R=207M". sabnzbd
+// reports none on windows & macOS, or when disabled.
+func parseLoadAverages(s string) ([]float64, error) {
+	fields := strings.Split(s, "|")
+	if len(fields) < 3 {
+		return nil, nil
+	}
+
+	var err error
+
+	ret := make([]float64, 3)
+	for i := range ret {
+		ret[i], err = parseFloat(strings.TrimSpace(fields[i]), err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServerStatsResponse is the response from the sabnzbd serverstats endpoint
+type ServerStatsResponse struct {
+	Total   int                           `json:"total"` // Total Data Downloaded in bytes
+	Day     int                           `json:"day"`   // Data Downloaded today in bytes
+	Week    int                           `json:"week"`  // Data Downloaded this week in bytes
+	Month   int                           `json:"month"` // Data Downloaded this month in bytes
+	Servers map[string]ServerStatResponse `json:"servers"`
+}
+
+type ServerStatResponse struct {
+	Total           int            `json:"total"`            // Total Data Downloaded in bytes
+	Day             int            `json:"day"`              // Data Downloaded today in bytes
+	Week            int            `json:"week"`             // Data Downloaded this week in bytes
+	Month           int            `json:"month"`            // Data Downloaded this month in bytes
+	Daily           map[string]int `json:"daily"`            // Data Downloaded per day in bytes (YYYY-MM-DD -> bytes)
+	ArticlesTried   map[string]int `json:"articles_tried"`   // Number of Articles Tried (YYYY-MM-DD -> count)
+	ArticlesSuccess map[string]int `json:"articles_success"` // Number of Articles Successfully Downloaded (YYYY-MM-DD -> count)
+}
+
+// QueueResponse is the response from the sabnzbd queue endpoint
+// Paused vs PausedAll -- as best I can tell, Paused is
+// "pause the queue but finish anything in flight"
+// PausedAll is "hard pause, including pausing in progress downloads"
+type QueueResponse struct {
+	Queue QueueResponseQueue `json:"queue"`
+}
+
+type QueueResponseQueue struct {
+	Version         string `json:"version"`         // version of Sabnzbd running
+	Paused          bool   `json:"paused"`          // Is the sabnzbd queue globally paused?
+	PauseInt        string `json:"pause_int"`       // returns minutes:seconds until sabnzbd is unpaused (minutes are unpadded)
+	PausedAll       bool   `json:"paused_all"`      // Paused All actions which causes disk activity
+	Diskspace1      string `json:"diskspace1"`      // Download Directory Used (float, MB)
+	Diskspace2      string `json:"diskspace2"`      // Completed Directory Used (float, MB)
+	DiskspaceTotal1 string `json:"diskspacetotal1"` // Download Directory Total (float, MB)
+	DiskspaceTotal2 string `json:"diskspacetotal2"` // Completed Directory Total (float, MB)
+	Speedlimit      string `json:"speedlimit"`      // The Speed Limit set as a percentage of configured line speed
+	SpeedlimitAbs   string `json:"speedlimit_abs"`  // The Speed Limit set in B/s
+	HaveWarnings    string `json:"have_warnings"`   // Number of Warnings present
+	Quota           string `json:"quota"`           // Total Quota configured (normalized to K/M/G/T/P)
+	HaveQuota       bool   `json:"have_quota"`      // Is a Periodic Quota set for Sabnzbd?
+	LeftQuota       string `json:"left_quota"`      // Quota Remaining (normalized to K/M/G/T/P)
+	CacheArt        string `json:"cache_art"`       // Number of Articles in Cache
+	CacheSize       string `json:"cache_size"`      // Size of Cache in bytes (normalized to "B/MB/GB/TB/PB")
+	KBPerSec        string `json:"kbpersec"`        // Float String representing Kbps
+	MBLeft          string `json:"mbleft"`          // Megabytes left to download in queue
+	MB              string `json:"mb"`              // total megabytes represented by queue
+	NoofSlotsTotal  int    `json:"noofslots_total"` // Total number of items in queue
+	Status          string `json:"status"`          // Status of sabnzbd (Paused, Idle, Downloading)
+	TimeLeft        string `json:"timeleft"`        // Estimated time to download all items in queue (HH:MM:SS)
+
+	Slots []QueueSlotResponse `json:"slots"` // Jobs in the queue
+
+	// Speed           string `json:"speed"`        // Float String normalized to B/K/M/G/T/P
+	// SizeLeft        string `json:"sizeleft"`     // Bytes left to download in queue (normalized to "B/KB/MB/GB/TB/PB")
+	// Size            string `json:"size"`         // total bytes represented by queue (normalized to "B/KB/MB/GB/TB/PB")
+	// Start           int    `json:"start"`        // Index of first item in queue (0 based)
+	//Limit           int    `json:"limit"`         // Number of items to return in response
+	//Finish          int    `json:"finish"`        // Index of last item in queue (0 based)
+	// NoofSlots int `json:"noofslots"` // Number of slots in api response (may be less than total if limit is set)
+}
+
+type QueueSlotResponse struct {
+	Index      int    `json:"index"`      // Position of the job in the queue (0 based)
+	NzoID      string `json:"nzo_id"`     // Id of the job
+	Filename   string `json:"filename"`   // Name of the job
+	Status     string `json:"status"`     // Status of the job (Queued, Paused, Downloading, Fetching, Propagating, ...)
+	Category   string `json:"cat"`        // Category of the job
+	Priority   string `json:"priority"`   // Priority of the job (Force, High, Normal, Low, ...)
+	MB         string `json:"mb"`         // Size of the job in megabytes (float)
+	MBLeft     string `json:"mbleft"`     // Megabytes left to download (float)
+	Percentage string `json:"percentage"` // Percentage of the job downloaded (int)
+	TimeLeft   string `json:"timeleft"`   // Estimated time to download the job (HH:MM:SS)
+}
+
+// HistoryResponse is the response from the sabnzbd history endpoint
+type HistoryResponse struct {
+	History HistoryResponseHistory `json:"history"`
+}
+
+type HistoryResponseHistory struct {
+	NoofSlots         int                   `json:"noofslots"`           // Total number of items in history matching the filters
+	PPSlots           int                   `json:"ppslots"`             // Number of items being post-processed
+	DaySize           string                `json:"day_size"`            // Downloaded today (normalized to K/M/G/T/P)
+	WeekSize          string                `json:"week_size"`           // Downloaded this week (normalized to K/M/G/T/P)
+	MonthSize         string                `json:"month_size"`          // Downloaded this month (normalized to K/M/G/T/P)
+	TotalSize         string                `json:"total_size"`          // Downloaded in total (normalized to K/M/G/T/P)
+	LastHistoryUpdate int64                 `json:"last_history_update"` // Id of the last change to the history
+	Slots             []HistorySlotResponse `json:"slots"`
+}
+
+type HistorySlotResponse struct {
+	NzoID        string `json:"nzo_id"`        // Id of the job
+	Name         string `json:"name"`          // Name of the job
+	NzbName      string `json:"nzb_name"`      // Name of the nzb file
+	Category     string `json:"category"`      // Category of the job
+	PP           string `json:"pp"`            // Post-processing option (R/U/D)
+	Script       string `json:"script"`        // User script run after post-processing
+	Status       string `json:"status"`        // Status of the job (Completed, Failed, Queued, Extracting, ...)
+	FailMessage  string `json:"fail_message"`  // Reason the job failed
+	Size         string `json:"size"`          // Size of the job (normalized to "B/KB/MB/GB/TB/PB")
+	Bytes        int64  `json:"bytes"`         // Size of the job in bytes
+	Downloaded   int64  `json:"downloaded"`    // Bytes downloaded for the job
+	DownloadTime int64  `json:"download_time"` // Time spent downloading in seconds
+	PostprocTime int64  `json:"postproc_time"` // Time spent post-processing in seconds
+	Completed    int64  `json:"completed"`     // Unix timestamp of completion
+	Storage      string `json:"storage"`       // Final location of the job
+}
+
+// WarningsResponse is the response from the sabnzbd warnings endpoint
+type WarningsResponse struct {
+	Warnings []WarningResponse `json:"warnings"`
+}
+
+type WarningResponse struct {
+	Text string `json:"text"` // Text of the warning
+	Type string `json:"type"` // Level of the warning (WARNING, ERROR)
+	Time int64  `json:"time"` // Unix timestamp of the warning
+}
+
+// UnmarshalJSON also accepts warnings in the format of sabnzbd 2.x, a string of
+// the time, level and text separated by newlines, e.g.
+// "2019-01-02 10:11:12,345\nWARNING\nDisk full".
+func (w *WarningResponse) UnmarshalJSON(b []byte) error {
+	var legacy string
+	if err := json.Unmarshal(b, &legacy); err == nil {
+		fields := strings.SplitN(legacy, "\n", 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("invalid warning: %q", legacy)
+		}
+
+		t, err := time.ParseInLocation("2006-01-02 15:04:05,000", fields[0], time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid warning time: %w", err)
+		}
+
+		*w = WarningResponse{Text: fields[2], Type: fields[1], Time: t.Unix()}
+
+		return nil
+	}
+
+	// Avoid recursing into UnmarshalJSON
+	type warningResponse WarningResponse
+
+	return json.Unmarshal(b, (*warningResponse)(w))
+}
+
+// StatusResponse is the response from the sabnzbd status & fullstatus endpoints
+type StatusResponse struct {
+	Status StatusResponseStatus `json:"status"`
+}
+
+type StatusResponseStatus struct {
+	LocalIPv4         string                 `json:"localipv4"`         // Local IPv4 address
+	IPv6              *string                `json:"ipv6"`              // Public IPv6 address, if any
+	PublicIPv4        *string                `json:"publicipv4"`        // Public IPv4 address, if any
+	DNSLookup         string                 `json:"dnslookup"`         // Result of the dns lookup check
+	CPUModel          string                 `json:"cpumodel"`          // Model of the cpu
+	Pystone           float64                `json:"pystone"`           // Pystone benchmark score
+	LoadAvg           string                 `json:"loadavg"`           // Load averages & memory usage, separated by "|"
+	DownloadDir       string                 `json:"downloaddir"`       // Incomplete download directory
+	DownloadDirSpeed  float64                `json:"downloaddirspeed"`  // Write speed of the download directory in MB/s
+	CompleteDir       string                 `json:"completedir"`       // Complete download directory
+	CompleteDirSpeed  float64                `json:"completedirspeed"`  // Write speed of the complete directory in MB/s
+	InternetBandwidth float64                `json:"internetbandwidth"` // Measured internet bandwidth in MB/s
+	Uptime            string                 `json:"uptime"`            // Uptime of sabnzbd
+	RestartReq        bool                   `json:"restart_req"`       // Does sabnzbd need to be restarted to apply its configuration?
+	PID               int                    `json:"pid"`               // Process id of sabnzbd
+	Servers           []StatusServerResponse `json:"servers"`
+}
+
+type StatusServerResponse struct {
+	ServerName       string `json:"servername"`       // Name of the news server
+	ServerActiveConn int    `json:"serveractiveconn"` // Number of active connections
+	ServerTotalConn  int    `json:"servertotalconn"`  // Number of configured connections
+	ServerSSLInfo    string `json:"serversslinfo"`    // TLS version & cipher in use
+	ServerActive     bool   `json:"serveractive"`     // Is the server enabled?
+	ServerError      string `json:"servererror"`      // Last error of the server, if any
+	ServerPriority   int    `json:"serverpriority"`   // Priority of the server (0 is highest)
+	ServerBPS        string `json:"serverbps"`        // Current speed (normalized to K/M/G/T/P)
+	ServerWarning    string `json:"-"`                // Warning reported instead of the active connections
+}
+
+// UnmarshalJSON accepts the text sabnzbd reports instead of the number of
+// active connections while a server is resolving its address or has a warning,
+// setting ServerWarning.
+func (s *StatusServerResponse) UnmarshalJSON(b []byte) error {
+	type statusServerResponse StatusServerResponse
+
+	aux := struct {
+		*statusServerResponse
+		ServerActiveConn json.RawMessage `json:"serveractiveconn"`
+	}{statusServerResponse: (*statusServerResponse)(s)}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	s.ServerActiveConn = 0
+	s.ServerWarning = ""
+
+	if len(aux.ServerActiveConn) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(aux.ServerActiveConn, &s.ServerActiveConn); err == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(aux.ServerActiveConn, &s.ServerWarning); err != nil {
+		return fmt.Errorf("invalid serveractiveconn: %s", aux.ServerActiveConn)
+	}
+
+	return nil
+}
+
+// VersionResponse is the response from the sabnzbd version endpoint
+type VersionResponse struct {
+	Version string `json:"version"`
+}
+
+// ConfigResponse is the response from the sabnzbd get_config endpoint. The
+// sections are left undecoded, as their content depends on the version of
+// sabnzbd.
+type ConfigResponse struct {
+	Config map[string]json.RawMessage `json:"config"`
+}
+
+// CategoriesResponse is the response from the sabnzbd get_cats endpoint
+type CategoriesResponse struct {
+	Categories []string `json:"categories"`
+}
+
+// ScriptsResponse is the response from the sabnzbd get_scripts endpoint
+type ScriptsResponse struct {
+	Scripts []string `json:"scripts"`
+}
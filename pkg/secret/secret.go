@@ -0,0 +1,176 @@
+// Package secret provides credentials which are either configured inline or
+// read from files, e.g. mounted kubernetes secrets. Files are watched, so
+// that rotated secrets are picked up without a restart.
+package secret
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// Secret is a credential whose value may change over time.
+type Secret interface {
+	Value() string
+}
+
+// Static is a Secret which never changes.
+type Static string
+
+func (s Static) Value() string {
+	return string(s)
+}
+
+// File is a Secret read from a file, and re-read whenever the file changes.
+// Surrounding whitespace, like the trailing newline most editors add, is
+// ignored.
+type File struct {
+	path string
+
+	lock  sync.RWMutex
+	value string
+}
+
+func (f *File) Value() string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.value
+}
+
+// Path returns the path of the file the secret is read from.
+func (f *File) Path() string {
+	return f.path
+}
+
+var (
+	filesLock sync.Mutex
+	files     = make(map[string]*File)
+)
+
+// FromFile returns the File secret read from path. Files are only read and
+// watched once, however often they're referenced.
+func FromFile(path string) (*File, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve secret file %s: %w", path, err)
+	}
+
+	filesLock.Lock()
+	defer filesLock.Unlock()
+
+	if f, ok := files[abs]; ok {
+		return f, nil
+	}
+
+	f := &File{path: abs}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+
+	if err := f.watch(); err != nil {
+		return nil, err
+	}
+
+	files[abs] = f
+
+	return f, nil
+}
+
+// Load returns value as Static secret, or the File secret read from path when
+// path is set.
+func Load(value, path string) (Secret, error) {
+	if path == "" {
+		return Static(value), nil
+	}
+
+	return FromFile(path)
+}
+
+// reload reads the file. The error never includes the file's content.
+func (f *File) reload() error {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("Failed to read secret file %s: %w", f.path, err)
+	}
+
+	value := strings.TrimSpace(string(b))
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if value == f.value {
+		return nil
+	}
+
+	// Files are commonly truncated before being rewritten.
+	if value == "" && f.value != "" {
+		return fmt.Errorf("Secret file %s is empty", f.path)
+	}
+
+	if f.value != "" {
+		log.Info().
+			Str("path", f.path).
+			Msg("Reloaded rotated secret")
+	}
+
+	f.value = value
+
+	return nil
+}
+
+// watch re-reads the file on any change within its directory. Watching the
+// directory rather than the file catches editors replacing the file, and
+// kubernetes swapping the symlink of a mounted secret.
+func (f *File) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Failed to watch secret file %s: %w", f.path, err)
+	}
+
+	if err := watcher.Add(filepath.Dir(f.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("Failed to watch secret file %s: %w", f.path, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				// Keep the previous value while the file is being replaced.
+				if err := f.reload(); err != nil {
+					log.Debug().
+						Err(err).
+						Str("path", f.path).
+						Msg("Failed to reload secret, keeping the previous value")
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Warn().
+					Err(err).
+					Str("path", f.path).
+					Msg("Failed to watch secret file")
+			}
+		}
+	}()
+
+	return nil
+}
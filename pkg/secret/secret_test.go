@@ -0,0 +1,131 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.FatalLevel)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func requireValue(t *testing.T, s Secret, expected string) {
+	require.Eventually(t, func() bool {
+		return s.Value() == expected
+	}, 2*time.Second, 10*time.Millisecond, "secret should be %q", expected)
+}
+
+func TestStatic(t *testing.T) {
+	require.Equal(t, "abc123", Static("abc123").Value())
+}
+
+func TestLoad(t *testing.T) {
+	require := require.New(t)
+
+	s, err := Load("abc123", "")
+	require.NoError(err)
+	require.Equal(Static("abc123"), s)
+
+	path := filepath.Join(t.TempDir(), "api_key")
+	writeFile(t, path, "def456\n")
+
+	s, err = Load("", path)
+	require.NoError(err)
+	require.Equal("def456", s.Value())
+
+	_, err = Load("", filepath.Join(t.TempDir(), "missing"))
+	require.Error(err)
+}
+
+func TestFromFile_SharedPerPath(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api_key")
+	writeFile(t, path, "abc123")
+
+	first, err := FromFile(path)
+	require.NoError(err)
+
+	second, err := FromFile(filepath.Join(dir, ".", "api_key"))
+	require.NoError(err)
+
+	require.Same(first, second)
+}
+
+func TestFromFile_ReloadsOnWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_key")
+	writeFile(t, path, "abc123\n")
+
+	s, err := FromFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "abc123", s.Value())
+
+	writeFile(t, path, "def456\n")
+	requireValue(t, s, "def456")
+}
+
+func TestFromFile_ReloadsOnReplace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api_key")
+	writeFile(t, path, "abc123")
+
+	s, err := FromFile(path)
+	require.NoError(t, err)
+
+	tmp := filepath.Join(dir, "api_key.tmp")
+	writeFile(t, tmp, "def456")
+	require.NoError(t, os.Rename(tmp, path))
+
+	requireValue(t, s, "def456")
+}
+
+// TestFromFile_ReloadsKubernetesSecret mimics how kubernetes updates mounted
+// secrets: the file is a symlink into a ..data symlink, which is swapped
+// atomically to point to a new directory.
+func TestFromFile_ReloadsKubernetesSecret(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+
+	for version, key := range map[string]string{"..v1": "abc123", "..v2": "def456"} {
+		require.NoError(os.Mkdir(filepath.Join(dir, version), 0o700))
+		writeFile(t, filepath.Join(dir, version, "api_key"), key)
+	}
+
+	require.NoError(os.Symlink("..v1", filepath.Join(dir, "..data")))
+	require.NoError(os.Symlink(filepath.Join("..data", "api_key"), filepath.Join(dir, "api_key")))
+
+	s, err := FromFile(filepath.Join(dir, "api_key"))
+	require.NoError(err)
+	require.Equal("abc123", s.Value())
+
+	require.NoError(os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	requireValue(t, s, "def456")
+}
+
+func TestFromFile_KeepsValueWhileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_key")
+	writeFile(t, path, "abc123")
+
+	s, err := FromFile(path)
+	require.NoError(t, err)
+
+	writeFile(t, path, "")
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, "abc123", s.Value())
+
+	writeFile(t, path, "def456")
+	requireValue(t, s, "def456")
+}